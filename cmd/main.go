@@ -9,9 +9,11 @@ import (
 	"summarybot/internal/bot"
 	"summarybot/internal/config"
 	"summarybot/internal/database"
+	"summarybot/internal/scheduler"
 	"summarybot/internal/services"
 	"summarybot/internal/utils"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/sashabaranov/go-openai"
 	"gopkg.in/telebot.v3"
@@ -41,9 +43,14 @@ func main() {
 
 	// сервисы
 	dialogSvc := services.NewDialogService(db, openaiClient, cfg.OpenAIModel, cfg.BotUsername)
-	summarySvc := services.NewSummaryService(db, openaiClient, cfg.OpenAIModel, cfg.MinMessagesForAI)
+	summarySvc := services.NewSummaryService(db, openaiClient, cfg.OpenAIModel, cfg.MaxTokens, cfg.MinMessagesForAI)
 	statsSvc := services.NewStatsService(db)
 	aiSvc := services.NewAIService(openaiClient, cfg.OpenAIModel)
+	settingsSvc := services.NewSettingsService(db, cfg.DefaultTimezone, cfg.DigestEnabled, cfg.DigestTime)
+	linkSvc := services.NewLinkService(db, openaiClient, cfg.OpenAIModel)
+	searchSvc := services.NewSearchService(db)
+	embeddingSvc := services.NewEmbeddingService(db, embeddingsBaseURL(cfg), cfg.OpenAIAPIKey, cfg.EmbeddingModel)
+	qaSvc := services.NewQAService(db, openaiClient, cfg.OpenAIModel, searchSvc, embeddingSvc)
 
 	// бот
 	pref := telebot.Settings{
@@ -56,7 +63,7 @@ func main() {
 		log.Fatalf("Ошибка создания Telegram бота: %v", err)
 	}
 
-	botApp := bot.New(cfg, db, tgBot, dialogSvc, summarySvc, statsSvc, aiSvc)
+	botApp := bot.New(cfg, db, tgBot, dialogSvc, summarySvc, statsSvc, aiSvc, settingsSvc, linkSvc, qaSvc, searchSvc, embeddingSvc)
 
 	// обработчики
 	registerHandlers(tgBot, botApp, cfg)
@@ -64,6 +71,14 @@ func main() {
 	// health сервер
 	go startHealthServer(cfg.Port)
 
+	// планировщик дайджестов
+	go scheduler.New(botApp, settingsSvc).Start()
+
+	// эмбеддинги для поиска по смыслу
+	if embeddingSvc.Enabled() {
+		go scheduler.NewEmbeddingJob(embeddingSvc).Start()
+	}
+
 	log.Printf("Бот запущен! Username: @%s", cfg.BotUsername)
 	tgBot.Start()
 }
@@ -79,17 +94,39 @@ func initDatabase(dbPath string) (*gorm.DB, error) {
 	// мигрируеммодели
 	err = db.AutoMigrate(
 		&database.Message{},
+		&database.MessageEdit{},
 		&database.ChatSummary{},
 		&database.AllowedChat{},
 		&database.ChatApprovalRequest{},
 		&database.SwearStats{},
 		&database.DialogContext{},
 		&database.UsedGreeting{},
+		&database.ChatSettings{},
+		&database.ForumTopic{},
+		&database.SharedLink{},
+		&database.MessageEmbedding{},
+		&database.JobCursor{},
+		&database.MessageContinuation{},
 	)
 
 	return db, err
 }
 
+// embeddingsBaseURL адрес OpenAI-совместимого API для /embeddings: отдельный (например, локальный сервер)
+// или тот же, что для чата. Пустой, если поиск по смыслу выключен
+func embeddingsBaseURL(cfg *config.Config) string {
+	switch {
+	case !cfg.EmbeddingsEnabled:
+		return ""
+	case cfg.EmbeddingsBaseURL != "":
+		return cfg.EmbeddingsBaseURL
+	case cfg.OpenAIBaseURL != "":
+		return cfg.OpenAIBaseURL
+	default:
+		return openai.DefaultConfig("").BaseURL
+	}
+}
+
 func registerHandlers(tgBot *telebot.Bot, botApp *bot.Bot, cfg *config.Config) {
 	// команды
 	tgBot.Handle("/start", botApp.HandleStart)
@@ -98,12 +135,39 @@ func registerHandlers(tgBot *telebot.Bot, botApp *bot.Bot, cfg *config.Config) {
 	tgBot.Handle("/reminder_random", botApp.HandleReminderRandom)
 	tgBot.Handle("/top_mat", botApp.HandleTopMat)
 	tgBot.Handle("/rap_name", botApp.HandleRapNik)
+	tgBot.Handle("/export", botApp.HandleExport)
+	tgBot.Handle("/search", botApp.HandleSearch)
+	// настройки чата
+	tgBot.Handle("/timezone", botApp.HandleTimezone)
+	tgBot.Handle("/digest", botApp.HandleDigest)
+	tgBot.Handle("/style", botApp.HandleStyle)
+	tgBot.Handle("/language", botApp.HandleLanguage)
+	// кнопки под резюме
+	for _, btn := range bot.SummaryButtons {
+		tgBot.Handle(btn, botApp.HandleSummaryButton)
+	}
+	tgBot.Handle(bot.SearchPageButton, botApp.HandleSearchPage)
 	// админские
 	tgBot.Handle("/approve", botApp.HandleApprove)
 	tgBot.Handle("/reject", botApp.HandleReject)
 	tgBot.Handle("/pending", botApp.HandlePending)
 	tgBot.Handle("/allowed", botApp.HandleAllowed)
 	tgBot.Handle(telebot.OnUserJoined, botApp.HandleUserJoined)
+	tgBot.Handle(telebot.OnTopicCreated, botApp.HandleTopicChange)
+	tgBot.Handle(telebot.OnTopicEdited, botApp.HandleTopicChange)
+	tgBot.Handle(telebot.OnEdited, botApp.HandleEdited)
+	// сообщения без текста: фото, видео, голосовые, файлы, стикеры и т.п., геопозиции, контакты
+	tgBot.Handle(telebot.OnMedia, botApp.HandleContent)
+	tgBot.Handle(telebot.OnLocation, botApp.HandleContent)
+	tgBot.Handle(telebot.OnVenue, botApp.HandleContent)
+	tgBot.Handle(telebot.OnContact, botApp.HandleContent)
+	// опросы telebot до обработчиков сообщений не доводит - забираем их прямо из потока обновлений
+	tgBot.Poller = telebot.NewMiddlewarePoller(tgBot.Poller, func(u *telebot.Update) bool {
+		if u.Message != nil && u.Message.Poll != nil {
+			botApp.SaveMessage(u.Message)
+		}
+		return true
+	})
 	tgBot.Handle(telebot.OnText, func(c telebot.Context) error {
 		message := c.Message()
 		botApp.SaveMessage(message)
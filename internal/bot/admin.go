@@ -1,11 +1,12 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 	"summarybot/internal/database"
+	"summarybot/internal/services"
 	"summarybot/internal/utils"
 	"time"
 
@@ -114,9 +115,7 @@ func (b *Bot) HandlePending(c telebot.Context) error {
 			req.ChatID, req.ChatID))
 	}
 
-	return c.Reply(response.String(), &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-	})
+	return b.replyHTML(c, response.String())
 }
 
 // HandleAllowed обработчик команды /allowed
@@ -146,73 +145,298 @@ func (b *Bot) HandleAllowed(c telebot.Context) error {
 		response.WriteString("📭 Нет разрешенных чатов.")
 	}
 
-	return c.Reply(response.String(), &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-	})
+	return b.replyHTML(c, response.String())
 }
 
 // HandleSummaryRequest обработчик запроса резюме
 func (b *Bot) HandleSummaryRequest(c telebot.Context) error {
 	message := c.Message()
+	lang := b.summaryLanguage(c.Chat().ID, message.Text)
+	texts := textsFor(lang)
 
 	if c.Chat().ID > 0 {
-		return c.Reply("⌛ Summary доступен только в групповых чатах, братан! 🤖")
+		return c.Reply(texts.groupOnly)
 	}
 
 	if !b.IsChatAllowed(c.Chat().ID) {
 		return b.handleUnauthorizedChat(c)
 	}
 
-	text := strings.ToLower(message.Text)
-	var days int
-	var period string
-
-	if strings.Contains(text, "сегодня") {
-		days = 0
-		period = "сегодня"
-	} else if strings.Contains(text, "вчера") {
-		days = 1
-		period = "вчера"
-	} else if strings.Contains(text, "позавчера") {
-		days = 2
-		period = "позавчера"
-	} else {
-		re := regexp.MustCompile(`(\d+)\s*дн`)
-		matches := re.FindStringSubmatch(text)
-		if len(matches) > 1 {
-			if d, err := strconv.Atoi(matches[1]); err == nil && d <= 7 {
-				days = d
-				period = fmt.Sprintf("%d дней назад", d)
-			} else {
-				return c.Reply("Могу показать резюме только за последние 7 дней 📅")
-			}
-		} else {
-			return c.Reply("Напиши '@zagichak_bot что было за сегодня/вчера/позавчера' или '@zagichak_bot что было за N дней' (макс 7)")
-		}
+	period, err := parseSummaryPeriod(message.Text, b.settingsSvc.Location(c.Chat().ID))
+	if errors.Is(err, errPeriodNotFound) {
+		return c.Reply(texts.usage)
+	}
+	if err != nil {
+		return c.Reply(fmt.Sprintf(texts.tooLong, maxSummaryDays))
+	}
+
+	statusMsg, _ := c.Bot().Send(c.Chat(), texts.generating, topicOptions(message))
+
+	// Пересобрать резюме принудительно могут только админы
+	req := services.SummaryRequest{
+		ChatID:   c.Chat().ID,
+		Period:   period,
+		TopicID:  messageTopic(message),
+		Force:    isForceRequest(message.Text) && b.IsChatAdmin(c.Chat(), c.Sender()),
+		Style:    b.settingsSvc.SummaryStyle(c.Chat().ID),
+		Language: lang,
+	}
+
+	result, err := b.summarySvc.GenerateSummary(req)
+	if err != nil {
+		c.Bot().Delete(statusMsg)
+		return c.Reply(texts.failed)
+	}
+
+	c.Bot().Delete(statusMsg)
+
+	count := b.summarySvc.CountMessages(req)
+
+	summaryText := fmt.Sprintf(texts.header, period.NameIn(lang), result.Text, count)
+
+	return b.replySummary(c, summaryText, result.ID, lang)
+}
+
+// maxCatchUpWindow насколько далеко назад смотрим в режиме "что я пропустил"
+const maxCatchUpWindow = 72 * time.Hour
+
+// HandleCatchUpRequest обработчик запроса "что я пропустил" -
+// резюме всего, что написали после последнего сообщения пользователя
+func (b *Bot) HandleCatchUpRequest(c telebot.Context) error {
+	message := c.Message()
+	lang := b.summaryLanguage(c.Chat().ID, message.Text)
+	texts := textsFor(lang)
+
+	if c.Chat().ID > 0 {
+		return c.Reply(texts.groupOnly)
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	loc := b.settingsSvc.Location(c.Chat().ID)
+	requestedAt := time.Unix(message.Unixtime, 0)
+	earliest := requestedAt.Add(-maxCatchUpWindow)
+
+	from, found := b.summarySvc.LastUserMessageTime(c.Chat().ID, message.Sender.ID, requestedAt)
+	capped := !found || from.Before(earliest)
+	if capped {
+		from = earliest
 	}
 
-	statusMsg, _ := c.Bot().Send(c.Chat(), "Генерирую резюме... ⏳")
+	period := services.SincePeriod(from, requestedAt, loc)
+
+	statusMsg, _ := c.Bot().Send(c.Chat(), texts.catchingUp, topicOptions(message))
+
+	req := services.SummaryRequest{
+		ChatID:   c.Chat().ID,
+		Period:   period,
+		TopicID:  messageTopic(message),
+		Style:    b.settingsSvc.SummaryStyle(c.Chat().ID),
+		Language: lang,
+	}
 
-	summary, err := b.summarySvc.GenerateSummary(c.Chat().ID, days)
+	result, err := b.summarySvc.GenerateSummary(req)
 	if err != nil {
 		c.Bot().Delete(statusMsg)
-		return c.Reply("Ошибка при создании резюме 😞")
+		return c.Reply(texts.failed)
 	}
 
 	c.Bot().Delete(statusMsg)
 
-	var count int64
-	startDate := time.Now().AddDate(0, 0, -days).Truncate(24 * time.Hour)
-	endDate := startDate.Add(24 * time.Hour)
-	b.db.Model(&database.Message{}).
-		Where("chat_id = ? AND timestamp >= ? AND timestamp < ?",
-			c.Chat().ID, startDate, endDate).
-		Count(&count)
+	count := b.summarySvc.CountMessages(req)
+
+	note := ""
+	if capped {
+		note = texts.catchUpCapped(int(maxCatchUpWindow.Hours()))
+	}
+
+	summaryText := fmt.Sprintf(texts.catchUpHeader, period.Start.Format("02.01 15:04"), result.Text, count, note)
+
+	return b.replySummary(c, summaryText, result.ID, lang)
+}
+
+// HandleParticipantSummaryRequest обработчик запроса "что писал @username за неделю"
+func (b *Bot) HandleParticipantSummaryRequest(c telebot.Context) error {
+	message := c.Message()
+	lang := b.summaryLanguage(c.Chat().ID, message.Text)
+	texts := textsFor(lang)
+
+	if c.Chat().ID > 0 {
+		return c.Reply(texts.groupOnly)
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	userID, userName, ok := b.findMentionedParticipant(message)
+	if !ok {
+		return c.Reply(texts.unknownUser)
+	}
+
+	loc := b.settingsSvc.Location(c.Chat().ID)
+	period, err := parseSummaryPeriod(message.Text, loc)
+	if errors.Is(err, errPeriodNotFound) {
+		period = services.LastDaysPeriod(maxSummaryDays, loc)
+	} else if err != nil {
+		return c.Reply(fmt.Sprintf(texts.tooLong, maxSummaryDays))
+	}
 
-	summaryText := fmt.Sprintf("📋 <b>Резюме за %s</b>\n\n%s\n\n<i>Проанализировано сообщений: %d</i>",
-		period, summary, count)
+	statusMsg, _ := c.Bot().Send(c.Chat(), texts.recalling, topicOptions(message))
+
+	req := services.SummaryRequest{
+		ChatID:   c.Chat().ID,
+		Period:   period,
+		TopicID:  messageTopic(message),
+		UserID:   userID,
+		UserName: userName,
+		Force:    isForceRequest(message.Text) && b.IsChatAdmin(c.Chat(), c.Sender()),
+		Style:    b.settingsSvc.SummaryStyle(c.Chat().ID),
+		Language: lang,
+	}
 
-	return c.Reply(summaryText, &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-	})
+	result, err := b.summarySvc.GenerateSummary(req)
+	if err != nil {
+		c.Bot().Delete(statusMsg)
+		return c.Reply(texts.failed)
+	}
+
+	c.Bot().Delete(statusMsg)
+
+	count := b.summarySvc.CountMessages(req)
+
+	summaryText := fmt.Sprintf(texts.participantHeader, utils.EscapeHTML(userName), period.NameIn(lang), result.Text, count)
+
+	return b.replySummary(c, summaryText, result.ID, lang)
+}
+
+// HandleThreadSummaryRequest обработчик "саммари треда" в ответ на сообщение:
+// резюме только той ветки ответов, в которую входит это сообщение
+func (b *Bot) HandleThreadSummaryRequest(c telebot.Context) error {
+	message := c.Message()
+	lang := b.summaryLanguage(c.Chat().ID, message.Text)
+	texts := textsFor(lang)
+
+	if c.Chat().ID > 0 {
+		return c.Reply(texts.groupOnly)
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	if message.ReplyTo == nil {
+		return c.Reply(texts.threadUsage)
+	}
+
+	rootID, period, ok := b.summarySvc.FindReplyThread(c.Chat().ID, message.ReplyTo.ID, b.settingsSvc.Location(c.Chat().ID))
+	if !ok {
+		return c.Reply(texts.threadNotFound)
+	}
+
+	statusMsg, _ := c.Bot().Send(c.Chat(), texts.readingThread, topicOptions(message))
+
+	req := services.SummaryRequest{
+		ChatID:       c.Chat().ID,
+		Period:       period,
+		ThreadRootID: rootID,
+		Style:        b.settingsSvc.SummaryStyle(c.Chat().ID),
+		Language:     lang,
+	}
+
+	result, err := b.summarySvc.GenerateSummary(req)
+	if err != nil {
+		c.Bot().Delete(statusMsg)
+		return c.Reply(texts.failed)
+	}
+
+	c.Bot().Delete(statusMsg)
+
+	count := b.summarySvc.CountMessages(req)
+
+	return b.replySummary(c, fmt.Sprintf(texts.threadHeader, result.Text, count), result.ID, lang)
+}
+
+// findMentionedParticipant находит участника, про которого спрашивают:
+// по упоминанию без username (text_mention) или по @username, кроме самого бота
+func (b *Bot) findMentionedParticipant(m *telebot.Message) (int64, string, bool) {
+	for _, entity := range m.Entities {
+		if entity.Type == telebot.EntityTMention && entity.User != nil {
+			return entity.User.ID, utils.GetUserDisplayName(entity.User), true
+		}
+	}
+
+	for _, entity := range m.Entities {
+		if entity.Type != telebot.EntityMention {
+			continue
+		}
+		username := strings.TrimPrefix(m.EntityText(entity), "@")
+		if strings.EqualFold(username, b.config.BotUsername) {
+			continue
+		}
+		if userID, name, ok := b.summarySvc.FindParticipant(m.Chat.ID, username); ok {
+			return userID, name, true
+		}
+	}
+
+	return 0, "", false
+}
+
+// maxSummaryDays максимальная длина периода для резюме в днях
+const maxSummaryDays = 7
+
+var (
+	// errPeriodNotFound в тексте запроса нет указания периода
+	errPeriodNotFound = errors.New("период не указан")
+	// errPeriodTooLong период длиннее maxSummaryDays
+	errPeriodTooLong = errors.New("слишком длинный период")
+)
+
+// parseSummaryPeriod определяет период резюме по тексту запроса
+func parseSummaryPeriod(text string, loc *time.Location) (services.Period, error) {
+	now := time.Now().In(loc)
+	r, ok := utils.ParsePeriod(text, now)
+	if !ok {
+		return services.Period{}, errPeriodNotFound
+	}
+
+	switch r.Kind {
+	case utils.RangeDay:
+		return services.DayPeriod(r.Days, loc), nil
+	case utils.RangeLastDays:
+		if r.Days > maxSummaryDays {
+			return services.Period{}, errPeriodTooLong
+		}
+		return services.LastDaysPeriod(r.Days, loc), nil
+	case utils.RangeDates:
+		if r.Days > maxSummaryDays {
+			return services.Period{}, errPeriodTooLong
+		}
+		return services.DateRangePeriod(r.Start, r.End.AddDate(0, 0, -1)), nil
+	default:
+		if r.End.Sub(r.Start) > maxSummaryDays*24*time.Hour {
+			return services.Period{}, errPeriodTooLong
+		}
+		if r.Hours > 0 {
+			return services.LastHoursPeriod(r.Hours, loc), nil
+		}
+		return services.HoursRangePeriod(r.Start, r.End, loc), nil
+	}
+}
+
+// forceFlag флаг принудительной пересборки резюме: "@bot что было сегодня --force"
+const forceFlag = "--force"
+
+// isForceRequest проверяет, просят ли пересобрать резюме заново: отдельным словом "заново"/"обнови"
+// или флагом --force. Целыми словами, чтобы "обновили" или "workforce" не запускали платную пересборку
+func isForceRequest(text string) bool {
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		switch strings.Trim(word, ".,!?:;()\"'«»") {
+		case "заново", "обнови", forceFlag:
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,28 @@
+package bot
+
+import "testing"
+
+func TestIsForceRequest(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"@bot что было за сегодня заново", true},
+		{"@bot обнови резюме за сегодня", true},
+		{"@bot что было сегодня, заново!", true},
+		{"@bot что было сегодня --force", true},
+		{"@bot что было за сегодня", false},
+		{"@bot что обновили за неделю", false},
+		{"@bot что было сегодня, надо обновить доки", false},
+		{"@bot что обсуждали про обновление", false},
+		{"@bot summary about the workforce today", false},
+		{"@bot summary, force majeure", false},
+		{"@bot что было --forced", false},
+	}
+
+	for _, tt := range tests {
+		if got := isForceRequest(tt.text); got != tt.want {
+			t.Errorf("isForceRequest(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
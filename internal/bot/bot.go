@@ -23,6 +23,11 @@ type Bot struct {
 	summarySvc  *services.SummaryService
 	statsSvc    *services.StatsService
 	aiSvc       *services.AIService
+	settingsSvc *services.SettingsService
+	linkSvc     *services.LinkService
+	qaSvc       *services.QAService
+	searchSvc   *services.SearchService
+	embedSvc    *services.EmbeddingService
 	greetingGen *utils.GreetingGenerator
 }
 
@@ -35,6 +40,11 @@ func New(
 	summarySvc *services.SummaryService,
 	statsSvc *services.StatsService,
 	aiSvc *services.AIService,
+	settingsSvc *services.SettingsService,
+	linkSvc *services.LinkService,
+	qaSvc *services.QAService,
+	searchSvc *services.SearchService,
+	embedSvc *services.EmbeddingService,
 ) *Bot {
 	return &Bot{
 		config:      cfg,
@@ -44,13 +54,19 @@ func New(
 		summarySvc:  summarySvc,
 		statsSvc:    statsSvc,
 		aiSvc:       aiSvc,
+		settingsSvc: settingsSvc,
+		linkSvc:     linkSvc,
+		qaSvc:       qaSvc,
+		searchSvc:   searchSvc,
+		embedSvc:    embedSvc,
 		greetingGen: utils.NewGreetingGenerator(),
 	}
 }
 
-// SaveMessage сохраняет сообщение в БД
+// SaveMessage сохраняет сообщение в БД; у медиа, опросов и прочего без текста - их текстовое представление
 func (b *Bot) SaveMessage(m *telebot.Message) {
-	if m.Text == "" {
+	contentType, text := messageContent(m)
+	if contentType == "" || m.Sender == nil {
 		return
 	}
 
@@ -59,13 +75,29 @@ func (b *Bot) SaveMessage(m *telebot.Message) {
 	}
 
 	message := database.Message{
-		ChatID:    m.Chat.ID,
-		UserID:    m.Sender.ID,
-		Username:  m.Sender.Username,
-		FirstName: m.Sender.FirstName,
-		Text:      m.Text,
-		Timestamp: time.Unix(m.Unixtime, 0),
-		CreatedAt: time.Now(),
+		ChatID:            m.Chat.ID,
+		TelegramMessageID: m.ID,
+		ChatUsername:      m.Chat.Username,
+		UserID:            m.Sender.ID,
+		Username:          m.Sender.Username,
+		FirstName:         m.Sender.FirstName,
+		Text:              text,
+		ContentType:       contentType,
+		ForwardedFrom:     messageForwardedFrom(m),
+		Timestamp:         time.Unix(m.Unixtime, 0).UTC(),
+		CreatedAt:         time.Now(),
+	}
+	// В форуме сообщения без ответа формально отвечают на создание темы - это не ответ
+	if m.ReplyTo != nil && !(m.TopicMessage && m.ReplyTo.ID == m.ThreadID) {
+		message.ReplyToMessageID = m.ReplyTo.ID
+	}
+	if m.TopicMessage {
+		message.TopicID = m.ThreadID
+		if m.ReplyTo != nil && m.ReplyTo.TopicCreated != nil {
+			if err := b.summarySvc.SaveTopicNameIfMissing(m.Chat.ID, m.ThreadID, m.ReplyTo.TopicCreated.Name); err != nil {
+				log.Printf("Ошибка сохранения названия темы %d в чате %d: %v", m.ThreadID, m.Chat.ID, err)
+			}
+		}
 	}
 
 	if err := b.db.Create(&message).Error; err != nil {
@@ -73,51 +105,70 @@ func (b *Bot) SaveMessage(m *telebot.Message) {
 	} else {
 		log.Printf("Сообщение сохранено: чат %d, пользователь %s (ID: %d)",
 			m.Chat.ID, utils.GetUserDisplayName(m.Sender), m.Sender.ID)
+		b.linkSvc.SaveLinks(message, messageURLs(m))
 	}
 
-	b.checkAndSaveSwearStats(m)
+	b.checkAndSaveSwearStats(m, text)
+}
+
+// swearWords маты, которые считаем для /top_mat
+var swearWords = []string{
+	"блять", "хуй", "пизда", "ебать", "сука", "говно", "дерьмо",
+	"мудак", "долбоеб", "ублюдок", "сволочь", "падла", "гавно",
+	"хрен", "херня", "охуеть", "заебать", "проебать", "наебать",
+	"пиздец", "ебаный", "хуевый", "пиздатый", "ебучий", "сраный",
+	"бля", "ебло", "хуило", "пидор", "пидарас", "гандон",
+}
+
+// swearWordsIn маты из списка, которые есть в тексте; каждый считается один раз на сообщение
+func swearWordsIn(text string) []string {
+	text = strings.ToLower(text)
+	var found []string
+	for _, swear := range swearWords {
+		if strings.Contains(text, swear) {
+			found = append(found, swear)
+		}
+	}
+	return found
 }
 
-// checkAndSaveSwearStats проверяет сообщение на мат и сохраняет статистику
-func (b *Bot) checkAndSaveSwearStats(m *telebot.Message) {
+// checkAndSaveSwearStats проверяет текст сообщения на мат и сохраняет статистику
+func (b *Bot) checkAndSaveSwearStats(m *telebot.Message, text string) {
 	if m.Chat.ID > 0 {
 		return
 	}
 
-	swearWords := []string{
-		"блять", "хуй", "пизда", "ебать", "сука", "говно", "дерьмо",
-		"мудак", "долбоеб", "ублюдок", "сволочь", "падла", "гавно",
-		"хрен", "херня", "охуеть", "заебать", "проебать", "наебать",
-		"пиздец", "ебаный", "хуевый", "пиздатый", "ебучий", "сраный",
-		"бля", "ебло", "хуило", "пидор", "пидарас", "гандон",
+	for _, swear := range swearWordsIn(text) {
+		b.addSwearCount(m.Chat.ID, m.Sender, swear, 1)
 	}
+}
 
-	text := strings.ToLower(m.Text)
-	for _, swear := range swearWords {
-		if strings.Contains(text, swear) {
-			var stat database.SwearStats
-			result := b.db.Where("chat_id = ? AND user_id = ? AND swear_word = ?",
-				m.Chat.ID, m.Sender.ID, swear).First(&stat)
-
-			if result.Error == nil {
-				b.db.Model(&stat).Updates(database.SwearStats{
-					Count:     stat.Count + 1,
-					FirstName: m.Sender.FirstName,
-					UpdatedAt: time.Now(),
-				})
-			} else {
-				newStat := database.SwearStats{
-					ChatID:    m.Chat.ID,
-					UserID:    m.Sender.ID,
-					Username:  m.Sender.Username,
-					FirstName: m.Sender.FirstName,
-					SwearWord: swear,
-					Count:     1,
-					UpdatedAt: time.Now(),
-				}
-				b.db.Create(&newStat)
-			}
+// addSwearCount меняет счетчик мата пользователя на delta; обнулившийся счетчик удаляется
+func (b *Bot) addSwearCount(chatID int64, user *telebot.User, swear string, delta int) {
+	var stat database.SwearStats
+	result := b.db.Where("chat_id = ? AND user_id = ? AND swear_word = ?",
+		chatID, user.ID, swear).First(&stat)
+
+	switch {
+	case result.Error == nil && stat.Count+delta <= 0:
+		b.db.Delete(&stat)
+	case result.Error == nil:
+		b.db.Model(&stat).Updates(database.SwearStats{
+			Count:     stat.Count + delta,
+			FirstName: user.FirstName,
+			UpdatedAt: time.Now(),
+		})
+	case delta > 0:
+		newStat := database.SwearStats{
+			ChatID:    chatID,
+			UserID:    user.ID,
+			Username:  user.Username,
+			FirstName: user.FirstName,
+			SwearWord: swear,
+			Count:     delta,
+			UpdatedAt: time.Now(),
 		}
+		b.db.Create(&newStat)
 	}
 }
 
@@ -135,6 +186,31 @@ func (b *Bot) IsChatAllowed(chatID int64) bool {
 	return count > 0
 }
 
+// AllowedChatIDs возвращает все разрешенные групповые чаты: из конфига и одобренные
+func (b *Bot) AllowedChatIDs() []int64 {
+	seen := make(map[int64]bool)
+	var ids []int64
+
+	add := func(chatID int64) {
+		if chatID < 0 && !seen[chatID] {
+			seen[chatID] = true
+			ids = append(ids, chatID)
+		}
+	}
+
+	for _, chatID := range b.config.AllowedChats {
+		add(chatID)
+	}
+
+	var chats []database.AllowedChat
+	b.db.Find(&chats)
+	for _, chat := range chats {
+		add(chat.ChatID)
+	}
+
+	return ids
+}
+
 // IsAdmin проверяет, является ли пользователь админом
 func (b *Bot) IsAdmin(userID int64) bool {
 	for _, adminID := range b.config.AdminUserIDs {
@@ -145,6 +221,21 @@ func (b *Bot) IsAdmin(userID int64) bool {
 	return false
 }
 
+// IsChatAdmin проверяет, является ли пользователь админом чата (или админом бота)
+func (b *Bot) IsChatAdmin(chat *telebot.Chat, user *telebot.User) bool {
+	if b.IsAdmin(user.ID) {
+		return true
+	}
+
+	member, err := b.telebot.ChatMemberOf(chat, user)
+	if err != nil {
+		log.Printf("Ошибка получения прав пользователя %d в чате %d: %v", user.ID, chat.ID, err)
+		return false
+	}
+
+	return member.Role == telebot.Creator || member.Role == telebot.Administrator
+}
+
 // RequestChatApproval создает запрос на одобрение чата
 func (b *Bot) RequestChatApproval(chatID int64, chatTitle string, userID int64, username, firstName string) {
 	// Проверяем, нет ли уже запроса
@@ -208,7 +299,7 @@ func (b *Bot) MaybeDoRandomAction(c telebot.Context) {
 		return
 	}
 
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
+	sevenDaysAgo := time.Now().AddDate(0, 0, -7).UTC()
 	var userCount int64
 	b.db.Raw(`
 		SELECT COUNT(DISTINCT user_id) 
@@ -235,9 +326,7 @@ func (b *Bot) MaybeDoRandomAction(c telebot.Context) {
 		}
 
 		message := fmt.Sprintf("%s %s", mention, roast)
-		c.Bot().Send(c.Chat(), message, &telebot.SendOptions{
-			ParseMode: telebot.ModeHTML,
-		})
+		b.sendHTML(c.Chat(), message, topicOptions(c.Message()))
 
 		log.Printf("Автоматический подкол для %s в чате %d",
 			utils.GetUserDisplayName(user), c.Chat().ID)
@@ -249,9 +338,7 @@ func (b *Bot) MaybeDoRandomAction(c telebot.Context) {
 
 		message := fmt.Sprintf("🔔 <b>Срочное напоминание:</b>\n\n%s %s",
 			mention, reminder)
-		c.Bot().Send(c.Chat(), message, &telebot.SendOptions{
-			ParseMode: telebot.ModeHTML,
-		})
+		b.sendHTML(c.Chat(), message, topicOptions(c.Message()))
 
 		log.Printf("Автоматическое напоминание для %s в чате %d",
 			utils.GetUserDisplayName(user), c.Chat().ID)
@@ -303,9 +390,7 @@ func (b *Bot) HandleReminderRandom(c telebot.Context) error {
 
 	message := fmt.Sprintf("⏰ <b>Важное напоминание:</b>\n\n%s %s", mention, reminder)
 
-	return c.Reply(message, &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-	})
+	return b.replyHTML(c, message)
 }
 
 func (b *Bot) HandleRapNik(c telebot.Context) error {
@@ -342,9 +427,7 @@ func (b *Bot) HandleRapNik(c telebot.Context) error {
 			"<i>Теперь ты готов покорять чарты!</i> 💿", nickname)
 	}
 
-	return c.Reply(message, &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-	})
+	return b.replyHTML(c, message)
 }
 
 // Вспомогательные тексты
@@ -359,12 +442,20 @@ func getAdminHelpText() string {
 
 <b>В групповых чатах:</b>
 • @zagichak_bot что было за сегодня/вчера - резюме чата
+• @zagichak_bot что было за сегодня заново - пересобрать резюме
 • @zagichak_bot [любое сообщение] - общение с ботом
 • Отвечай на сообщения бота - веди диалог! 💬
 • /roast_random - подкол случайному пользователю 🔥
 • /reminder_random - напоминание кому-то 😁  
 • /top_mat - топ матершинников чата 🤬
 • /rap_name - генератор рэп-псевдонимов 🎤
+• /export [период] [html] - резюме и переписка файлом 📦
+• /search запрос - поиск по старым сообщениям 🔎
+• /search --semantic запрос - поиск по смыслу 🧠
+• /timezone &lt;зона&gt; - таймзона чата 🕰
+• /digest on|off|ЧЧ:ММ - ежедневный дайджест 🌅
+• /style - стиль резюме ✍️
+• /language - язык резюме 🌍
 
 
 Бот работает только в разрешенных групповых чатах! 🤖`
@@ -391,7 +482,15 @@ func getGroupHelpText() string {
 • @zagichak_bot что было за сегодня
 • @zagichak_bot что было за вчера  
 • @zagichak_bot что было за позавчера
-• @zagichak_bot что было за 3 дня (макс 7)
+• @zagichak_bot что было за 3 дня - последние дни целиком (макс 7)
+• @zagichak_bot что было 10.10 - 12.10 - за конкретные даты
+• @zagichak_bot что было за прошлую неделю / с понедельника
+• @zagichak_bot что было за последние 3 часа / с 10 до 14
+• @zagichak_bot что я пропустил - всё после твоего последнего сообщения
+• @zagichak_bot что писал @username за неделю - о чем писал один человек
+• @zagichak_bot ссылки за неделю - подборка ссылок по сайтам
+• @zagichak_bot кто говорил про отпуск? - найду в истории, кто и когда
+• ответ на сообщение + @zagichak_bot саммари треда - резюме одной ветки ответов
 
 <b>Общение:</b>
 • @zagichak_bot [любое сообщение] - поболтать с ботом
@@ -403,6 +502,16 @@ func getGroupHelpText() string {
 • /reminder_random - "важное" напоминание кому-то 😁
 • /top_mat - топ матершинников чата 🤬
 • /rap_name - генератор рэп-псевдонимов 🎤
+• /export [период] [html] - резюме и переписка файлом 📦
+• /search запрос - поиск по старым сообщениям 🔎
+• /search --semantic запрос - поиск по смыслу 🧠
+
+<b>Настройки (для админов чата):</b>
+• /timezone Europe/Moscow - таймзона чата для резюме
+• /digest on|off|09:00 - ежедневный дайджест за вчера
+• /style slang|neutral|business|bullets - стиль резюме
+• /language ru|en - язык резюме (для одного запроса: «in english»)
+• @zagichak_bot что было за сегодня заново - пересобрать резюме
 
 Я анализирую сообщения, делаю крутые резюме и веду живые диалоги! 🤖✨`
 }
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"summarybot/internal/database"
+	"summarybot/internal/utils"
+
+	"gopkg.in/telebot.v3"
+)
+
+// messageContent тип сообщения и его текстовое представление для БД: текст, подпись к медиа,
+// вопрос и варианты опроса, эмодзи стикера, название файла, место.
+// Пустой тип - сообщение не из тех, что стоит сохранять
+func messageContent(m *telebot.Message) (string, string) {
+	switch {
+	case m.Text != "":
+		return database.ContentText, m.Text
+	case m.Poll != nil:
+		lines := []string{m.Poll.Question}
+		for _, option := range m.Poll.Options {
+			lines = append(lines, "— "+option.Text)
+		}
+		return database.ContentPoll, strings.Join(lines, "\n")
+	case m.Sticker != nil:
+		return database.ContentSticker, m.Sticker.Emoji
+	case m.Photo != nil:
+		return database.ContentPhoto, m.Caption
+	case m.Video != nil:
+		return database.ContentVideo, m.Caption
+	// гифка приходит и как документ, поэтому проверяем до документа
+	case m.Animation != nil:
+		return database.ContentAnimation, m.Caption
+	case m.VideoNote != nil:
+		return database.ContentVideoNote, ""
+	case m.Voice != nil:
+		return database.ContentVoice, m.Caption
+	case m.Audio != nil:
+		track := joinNonEmpty(" - ", m.Audio.Performer, m.Audio.Title)
+		return database.ContentAudio, joinNonEmpty("\n", track, m.Caption)
+	case m.Document != nil:
+		return database.ContentDocument, joinNonEmpty("\n", m.Document.FileName, m.Caption)
+	// у места есть и геопозиция, поэтому проверяем до нее
+	case m.Venue != nil:
+		return database.ContentVenue, joinNonEmpty(", ", m.Venue.Title, m.Venue.Address)
+	case m.Location != nil:
+		return database.ContentLocation, fmt.Sprintf("%.5f, %.5f", m.Location.Lat, m.Location.Lng)
+	case m.Contact != nil:
+		return database.ContentContact, joinNonEmpty(" ", m.Contact.FirstName, m.Contact.LastName)
+	}
+	return "", ""
+}
+
+// messageForwardedFrom от кого переслано сообщение: пользователь, канал или скрытое имя; пустое - не пересланное
+func messageForwardedFrom(m *telebot.Message) string {
+	switch {
+	case m.OriginalSender != nil:
+		return utils.GetUserDisplayName(m.OriginalSender)
+	case m.OriginalChat != nil:
+		return m.OriginalChat.Title
+	default:
+		return m.OriginalSenderName
+	}
+}
+
+// messageEntities сущности текста или подписи к медиа
+func messageEntities(m *telebot.Message) telebot.Entities {
+	if m.Text == "" {
+		return m.CaptionEntities
+	}
+	return m.Entities
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+// HandleContent обработчик сообщений без текста: медиа, стикеры, геопозиции, контакты - только сохраняем
+func (b *Bot) HandleContent(c telebot.Context) error {
+	b.SaveMessage(c.Message())
+	return nil
+}
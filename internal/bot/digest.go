@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"summarybot/internal/services"
+
+	"gopkg.in/telebot.v3"
+)
+
+// SendDailyDigest публикует в чат резюме за вчерашний день
+func (b *Bot) SendDailyDigest(chatID int64) error {
+	period := services.DayPeriod(1, b.settingsSvc.Location(chatID))
+	lang := b.settingsSvc.Language(chatID)
+	req := services.SummaryRequest{
+		ChatID:   chatID,
+		Period:   period,
+		Style:    b.settingsSvc.SummaryStyle(chatID),
+		Language: lang,
+	}
+
+	count := b.summarySvc.CountMessages(req)
+	if count < int64(b.config.MinMessagesForAI) {
+		log.Printf("Дайджест для чата %d пропущен: вчера было всего %d сообщений", chatID, count)
+		return nil
+	}
+
+	result, err := b.summarySvc.GenerateSummary(req)
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf(textsFor(lang).digestHeader, period.NameIn(lang), period.Start.Format("02.01"), result.Text, count)
+
+	_, err = b.sendHTMLEditable(&telebot.Chat{ID: chatID}, text, &telebot.SendOptions{
+		ReplyMarkup: summaryMarkup(result.ID, lang),
+	})
+	if err == nil {
+		log.Printf("Дайджест отправлен в чат %d", chatID)
+	}
+	return err
+}
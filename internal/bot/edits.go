@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"log"
+	"summarybot/internal/database"
+	"time"
+
+	"gopkg.in/telebot.v3"
+	"gorm.io/gorm"
+)
+
+// HandleEdited обработчик правки сообщения или подписи к медиа: в БД кладем новый текст,
+// старый уходит в историю правок, статистика мата пересчитывается на разницу между старой и новой версией
+func (b *Bot) HandleEdited(c telebot.Context) error {
+	m := c.Message()
+	if m == nil || m.Sender == nil || !b.IsChatAllowed(m.Chat.ID) {
+		return nil
+	}
+
+	// Живая геопозиция "редактируется" каждые несколько секунд - это не правка
+	contentType, text := messageContent(m)
+	if contentType == "" || contentType == database.ContentLocation {
+		return nil
+	}
+
+	var stored database.Message
+	err := b.db.Where("chat_id = ? AND telegram_message_id = ?", m.Chat.ID, m.ID).
+		Limit(1).Find(&stored).Error
+	if err != nil {
+		log.Printf("Ошибка поиска отредактированного сообщения %d в чате %d: %v", m.ID, m.Chat.ID, err)
+		return nil
+	}
+
+	// Сообщение написано до того, как бот его увидел - сохраняем как новое
+	if stored.ID == 0 {
+		b.SaveMessage(m)
+		return nil
+	}
+
+	if stored.Text == text {
+		return nil
+	}
+
+	editedAt := time.Now().UTC()
+	if m.LastEdit != 0 {
+		editedAt = time.Unix(m.LastEdit, 0).UTC()
+	}
+
+	oldText := stored.Text
+	err = b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&database.MessageEdit{
+			MessageID:         stored.ID,
+			ChatID:            stored.ChatID,
+			TelegramMessageID: stored.TelegramMessageID,
+			Text:              oldText,
+			EditedAt:          editedAt,
+			CreatedAt:         time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&stored).Update("text", text).Error
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения правки сообщения %d в чате %d: %v", m.ID, m.Chat.ID, err)
+		return nil
+	}
+	stored.Text = text
+
+	log.Printf("Сообщение %d в чате %d отредактировано", m.ID, m.Chat.ID)
+
+	b.updateSwearStats(m, oldText, text)
+	b.linkSvc.ReplaceLinks(stored, messageURLs(m))
+	go func() {
+		if err := b.embedSvc.Refresh(stored); err != nil {
+			log.Printf("Ошибка пересчета эмбеддинга сообщения %d: %v", stored.ID, err)
+		}
+	}()
+	return nil
+}
+
+// updateSwearStats учитывает в статистике мата только то, что поменялось при правке:
+// добавленные маты прибавляются, убранные - вычитаются
+func (b *Bot) updateSwearStats(m *telebot.Message, oldText, newText string) {
+	if m.Chat.ID > 0 {
+		return
+	}
+
+	was := make(map[string]bool)
+	for _, swear := range swearWordsIn(oldText) {
+		was[swear] = true
+	}
+
+	for _, swear := range swearWordsIn(newText) {
+		if was[swear] {
+			delete(was, swear)
+			continue
+		}
+		b.addSwearCount(m.Chat.ID, m.Sender, swear, 1)
+	}
+	for swear := range was {
+		b.addSwearCount(m.Chat.ID, m.Sender, swear, -1)
+	}
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"summarybot/internal/services"
+
+	"gopkg.in/telebot.v3"
+)
+
+// HandleExport обработчик "@bot экспорт за неделю" и команды /export:
+// присылает файл с резюме и полной перепиской за период (Markdown, или HTML, если попросили)
+func (b *Bot) HandleExport(c telebot.Context) error {
+	message := c.Message()
+	lang := b.summaryLanguage(c.Chat().ID, message.Text)
+	texts := textsFor(lang)
+
+	if c.Chat().ID > 0 {
+		return c.Reply(texts.groupOnly)
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	loc := b.settingsSvc.Location(c.Chat().ID)
+	period, err := parseSummaryPeriod(message.Text, loc)
+	if errors.Is(err, errPeriodNotFound) {
+		period = services.DayPeriod(0, loc)
+	} else if err != nil {
+		return c.Reply(fmt.Sprintf(texts.tooLong, maxSummaryDays))
+	}
+
+	format := services.ExportMarkdown
+	if strings.Contains(strings.ToLower(message.Text), "html") {
+		format = services.ExportHTML
+	}
+
+	req := services.SummaryRequest{
+		ChatID:   c.Chat().ID,
+		Period:   period,
+		TopicID:  messageTopic(message),
+		Style:    b.settingsSvc.SummaryStyle(c.Chat().ID),
+		Language: lang,
+	}
+
+	if b.summarySvc.CountMessages(req) == 0 {
+		return c.Reply(fmt.Sprintf(texts.exportEmpty, period.NameIn(lang)))
+	}
+
+	statusMsg, _ := c.Bot().Send(c.Chat(), texts.exporting, topicOptions(message))
+
+	export, err := b.summarySvc.ExportSummary(req, format, fmt.Sprintf(texts.exportTitle, period.NameIn(lang)))
+	c.Bot().Delete(statusMsg)
+	if err != nil {
+		log.Printf("Ошибка выгрузки резюме чата %d: %v", c.Chat().ID, err)
+		return c.Reply(texts.failed)
+	}
+
+	return c.Reply(&telebot.Document{
+		File:     telebot.FromReader(bytes.NewReader(export.Content)),
+		FileName: export.FileName,
+		MIME:     export.MIME,
+		Caption:  fmt.Sprintf(texts.exportCaption, period.NameIn(lang), export.Messages),
+	})
+}
@@ -71,6 +71,36 @@ func (b *Bot) HandleMentions(c telebot.Context) error {
 	log.Printf("Обнаружено упоминание бота от %s: %s",
 		utils.GetUserDisplayName(message.Sender), message.Text)
 
+	// Проверяем, просят ли подборку ссылок
+	if utils.IsLinksRequest(message.Text) {
+		return b.HandleLinksRequest(c)
+	}
+
+	// Проверяем, спрашивают ли про что-то из прошлого переписки
+	if utils.IsHistoryQuestion(message.Text) {
+		return b.HandleHistoryQuestion(c)
+	}
+
+	// Проверяем, просят ли пересказать ветку ответов
+	if utils.IsThreadSummaryRequest(message.Text) {
+		return b.HandleThreadSummaryRequest(c)
+	}
+
+	// Проверяем, спрашивают ли что пропустили
+	if utils.IsCatchUpRequest(message.Text) {
+		return b.HandleCatchUpRequest(c)
+	}
+
+	// Проверяем, спрашивают ли про конкретного участника
+	if utils.IsParticipantSummaryRequest(message.Text) {
+		return b.HandleParticipantSummaryRequest(c)
+	}
+
+	// Проверяем, просят ли выгрузку файлом
+	if utils.IsExportRequest(message.Text) {
+		return b.HandleExport(c)
+	}
+
 	// Проверяем, это запрос резюме?
 	if utils.IsSummaryRequest(message.Text) {
 		return b.HandleSummaryRequest(c)
@@ -98,7 +128,7 @@ func (b *Bot) HandleMentions(c telebot.Context) error {
 		response = "Братан, не расслышал! Повтори еще раз 👂"
 	}
 
-	sentMessage, err := c.Bot().Send(c.Chat(), response, &telebot.SendOptions{
+	sentMessage, err := b.sendHTML(c.Chat(), response, &telebot.SendOptions{
 		ReplyTo: message,
 	})
 
@@ -175,7 +205,7 @@ func (b *Bot) HandleBotReply(c telebot.Context) error {
 		response = "Секунду, обрабатываю... 🤔"
 	}
 
-	sentMessage, err := c.Bot().Send(c.Chat(), response, &telebot.SendOptions{
+	sentMessage, err := b.sendHTML(c.Chat(), response, &telebot.SendOptions{
 		ReplyTo: message,
 	})
 
@@ -219,9 +249,7 @@ func (b *Bot) HandleRoastRandom(c telebot.Context) error {
 
 	message := fmt.Sprintf("%s %s", mention, roast)
 
-	return c.Reply(message, &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-	})
+	return b.replyHTML(c, message)
 }
 
 // HandleTopMat обработчик команды /top_mat
@@ -259,9 +287,7 @@ func (b *Bot) HandleTopMat(c telebot.Context) error {
 
 	response.WriteString("\n<i>Статистика ведется с момента последнего обновления бота 📊</i>")
 
-	return c.Reply(response.String(), &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
-	})
+	return b.replyHTML(c, response.String())
 }
 
 // Вспомогательные функции для текстов
@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"summarybot/internal/services"
+	"summarybot/internal/utils"
+
+	"gopkg.in/telebot.v3"
+)
+
+// maxLinkTitleRunes длина ссылки в подборке, дальше обрезаем
+const maxLinkTitleRunes = 60
+
+// messageURLs ссылки из сообщения или подписи к медиа: сущности url и text_link от Telegram,
+// плюс то, что нашлось в тексте
+func messageURLs(m *telebot.Message) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	add := func(link string) {
+		if link != "" && !seen[link] {
+			seen[link] = true
+			urls = append(urls, link)
+		}
+	}
+
+	for _, entity := range messageEntities(m) {
+		switch entity.Type {
+		case telebot.EntityURL:
+			add(utils.NormalizeURL(m.EntityText(entity)))
+		case telebot.EntityTextLink:
+			add(utils.NormalizeURL(entity.URL))
+		}
+	}
+	for _, link := range utils.ExtractURLs(m.Text + "\n" + m.Caption) {
+		add(link)
+	}
+
+	return urls
+}
+
+// HandleLinksRequest обработчик "@bot ссылки за неделю" - подборка ссылок по доменам
+// с автором и коротким описанием
+func (b *Bot) HandleLinksRequest(c telebot.Context) error {
+	message := c.Message()
+	lang := b.summaryLanguage(c.Chat().ID, message.Text)
+	texts := textsFor(lang)
+
+	if c.Chat().ID > 0 {
+		return c.Reply(texts.groupOnly)
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	loc := b.settingsSvc.Location(c.Chat().ID)
+	period, err := parseSummaryPeriod(message.Text, loc)
+	if errors.Is(err, errPeriodNotFound) {
+		period = services.LastDaysPeriod(maxSummaryDays, loc)
+	} else if err != nil {
+		return c.Reply(fmt.Sprintf(texts.tooLong, maxSummaryDays))
+	}
+
+	statusMsg, _ := c.Bot().Send(c.Chat(), texts.collectingLinks, topicOptions(message))
+
+	groups, err := b.linkSvc.LinkDigest(c.Chat().ID, messageTopic(message), period, lang)
+	c.Bot().Delete(statusMsg)
+	if err != nil {
+		log.Printf("Ошибка подборки ссылок чата %d: %v", c.Chat().ID, err)
+		return c.Reply(texts.failed)
+	}
+
+	if len(groups) == 0 {
+		return c.Reply(fmt.Sprintf(texts.linksEmpty, period.NameIn(lang)))
+	}
+
+	return b.replyHTML(c, linksDigestText(groups, period, lang))
+}
+
+// linksDigestText собирает подборку ссылок в HTML
+func linksDigestText(groups []services.LinkGroup, period services.Period, lang services.Language) string {
+	texts := textsFor(lang)
+
+	total := 0
+	for _, group := range groups {
+		total += len(group.Links)
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf(texts.linksHeader, period.NameIn(lang), total))
+
+	for _, group := range groups {
+		text.WriteString(fmt.Sprintf("\n🌐 <b>%s</b>\n", utils.EscapeHTML(group.Domain)))
+		for _, link := range group.Links {
+			text.WriteString(fmt.Sprintf("• <a href=\"%s\">%s</a>", utils.EscapeHTML(link.URL), utils.EscapeHTML(linkTitle(link.URL))))
+			if link.Description != "" {
+				text.WriteString(" - " + utils.EscapeHTML(link.Description))
+			}
+
+			name := link.FirstName
+			if name == "" {
+				name = link.Username
+			}
+			meta := fmt.Sprintf("%s, %s", name, link.Timestamp.In(period.Location()).Format("02.01"))
+			if link.Shares > 1 {
+				meta += fmt.Sprintf(" ×%d", link.Shares)
+			}
+			text.WriteString(fmt.Sprintf("\n  <i>%s</i>\n", utils.EscapeHTML(meta)))
+		}
+	}
+
+	return text.String()
+}
+
+// linkTitle короткая подпись ссылки: без протокола и обрезанная до maxLinkTitleRunes
+func linkTitle(link string) string {
+	title := strings.TrimPrefix(strings.TrimPrefix(link, "https://"), "http://")
+	title = strings.TrimPrefix(title, "www.")
+	if runes := []rune(title); len(runes) > maxLinkTitleRunes {
+		title = string(runes[:maxLinkTitleRunes]) + "…"
+	}
+	return title
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"summarybot/internal/services"
+	"summarybot/internal/utils"
+
+	"gopkg.in/telebot.v3"
+)
+
+// HandleHistoryQuestion обработчик вопросов о прошлом переписки ("кто говорил про отпуск и когда?"):
+// ответ по найденным сообщениям с автором, датой и ссылками на сообщения
+func (b *Bot) HandleHistoryQuestion(c telebot.Context) error {
+	message := c.Message()
+	lang := b.summaryLanguage(c.Chat().ID, message.Text)
+	texts := textsFor(lang)
+
+	if c.Chat().ID > 0 {
+		return c.Reply(texts.groupOnly)
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	loc := b.settingsSvc.Location(c.Chat().ID)
+	req := services.QuestionRequest{
+		ChatID:   c.Chat().ID,
+		TopicID:  messageTopic(message),
+		Question: utils.TrimBotUsername(message.Text, b.config.BotUsername),
+		Language: lang,
+		Location: loc,
+	}
+	// Период в вопросе необязателен: "что решили про шашлыки на прошлой неделе"
+	if period, err := parseSummaryPeriod(message.Text, loc); err == nil {
+		req.Period = period
+	} else if !errors.Is(err, errPeriodNotFound) {
+		return c.Reply(fmt.Sprintf(texts.tooLong, maxSummaryDays))
+	}
+
+	statusMsg, _ := c.Bot().Send(c.Chat(), texts.searchingHistory, topicOptions(message))
+
+	answer, err := b.qaSvc.Answer(req)
+	c.Bot().Delete(statusMsg)
+	if err != nil {
+		log.Printf("Ошибка ответа на вопрос в чате %d: %v", c.Chat().ID, err)
+		return c.Reply(texts.failed)
+	}
+
+	if answer.Found == 0 || answer.Text == "" {
+		return c.Reply(texts.qaNothing)
+	}
+
+	return b.replyHTML(c, questionAnswerText(answer, texts))
+}
+
+// questionAnswerText ответ на вопрос со списком сообщений-источников
+func questionAnswerText(answer services.QuestionAnswer, texts summaryBotTexts) string {
+	var text strings.Builder
+	text.WriteString("🔎 " + utils.EscapeHTML(answer.Text))
+
+	if len(answer.Sources) > 0 {
+		text.WriteString("\n\n" + texts.qaSources + "\n")
+		for _, source := range answer.Sources {
+			label := utils.EscapeHTML(fmt.Sprintf("%s, %s", source.Author, source.Time.Format("02.01.2006 15:04")))
+			if source.Link != "" {
+				label = fmt.Sprintf("<a href=\"%s\">%s</a>", source.Link, label)
+			}
+			text.WriteString("• " + label + "\n")
+		}
+	}
+
+	return text.String()
+}
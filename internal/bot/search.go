@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"summarybot/internal/services"
+	"summarybot/internal/utils"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	searchBtnPage = "search_page"
+	// searchPageSize сколько результатов поиска на одной странице
+	searchPageSize = 5
+	// semanticFlag флаг поиска по смыслу: /search --semantic запрос
+	semanticFlag = "--semantic"
+)
+
+// SearchPageButton кнопки листания результатов поиска, для регистрации обработчика
+var SearchPageButton = &telebot.Btn{Unique: searchBtnPage}
+
+// HandleSearch обработчик команды /search <запрос> - полнотекстовый поиск по сообщениям чата,
+// с --semantic - поиск по смыслу
+func (b *Bot) HandleSearch(c telebot.Context) error {
+	texts := textsFor(b.settingsSvc.Language(c.Chat().ID))
+
+	if c.Chat().ID > 0 {
+		return c.Reply(texts.groupOnly)
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	raw := searchQueryOf(c.Message())
+	query, semantic := semanticQuery(raw)
+	if len(utils.SearchTerms(query)) == 0 {
+		return c.Reply(texts.searchUsage, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	}
+	if semantic && !b.embedSvc.Enabled() {
+		return c.Reply(texts.semanticOff, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	}
+
+	text, markup, err := b.searchPageText(c.Chat().ID, messageTopic(c.Message()), raw, 0)
+	if err != nil {
+		log.Printf("Ошибка поиска в чате %d: %v", c.Chat().ID, err)
+		return c.Reply(texts.failed)
+	}
+
+	_, err = b.sendHTMLEditable(c.Chat(), text, &telebot.SendOptions{
+		ReplyTo:               c.Message(),
+		ReplyMarkup:           markup,
+		DisableWebPagePreview: true,
+	})
+	return err
+}
+
+// HandleSearchPage обработчик кнопок листания результатов поиска.
+// Запрос и тема форума берутся из команды, на которую ответило сообщение с результатами
+func (b *Bot) HandleSearchPage(c telebot.Context) error {
+	cb := c.Callback()
+	if cb == nil || cb.Message == nil || !b.IsChatAllowed(c.Chat().ID) {
+		return c.Respond()
+	}
+
+	texts := textsFor(b.settingsSvc.Language(c.Chat().ID))
+
+	page, err := strconv.Atoi(cb.Data)
+	if err != nil || page < 0 || cb.Message.ReplyTo == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: texts.searchExpired})
+	}
+
+	command := cb.Message.ReplyTo
+	text, markup, err := b.searchPageText(c.Chat().ID, messageTopic(command), searchQueryOf(command), page)
+	if err != nil {
+		log.Printf("Ошибка поиска в чате %d: %v", c.Chat().ID, err)
+		return c.Respond(&telebot.CallbackResponse{Text: texts.failed})
+	}
+
+	c.Respond()
+	return b.editHTML(cb.Message, text, markup)
+}
+
+// searchQueryOf текст запроса из команды "/search запрос"
+func searchQueryOf(m *telebot.Message) string {
+	text := strings.TrimSpace(m.Text)
+	if !strings.HasPrefix(text, "/") {
+		return text
+	}
+	if i := strings.IndexAny(text, " \n"); i >= 0 {
+		return strings.TrimSpace(text[i:])
+	}
+	return ""
+}
+
+// semanticQuery убирает из запроса флаг --semantic и сообщает, был ли он
+func semanticQuery(query string) (string, bool) {
+	fields := strings.Fields(query)
+	semantic := false
+	kept := fields[:0]
+	for _, field := range fields {
+		if strings.EqualFold(field, semanticFlag) {
+			semantic = true
+			continue
+		}
+		kept = append(kept, field)
+	}
+	return strings.Join(kept, " "), semantic
+}
+
+// searchPageText страница результатов поиска и кнопки листания.
+// Запрос берется целиком, с флагом --semantic, если он есть; в форуме ищем только в теме topicID
+func (b *Bot) searchPageText(chatID int64, topicID int, query string, page int) (string, *telebot.ReplyMarkup, error) {
+	texts := textsFor(b.settingsSvc.Language(chatID))
+
+	query, semantic := semanticQuery(query)
+	searchQuery := services.SearchQuery{
+		ChatID:  chatID,
+		TopicID: topicID,
+		Terms:   utils.SearchTerms(query),
+		Offset:  page * searchPageSize,
+		Limit:   searchPageSize,
+	}
+
+	var (
+		result services.SearchPage
+		err    error
+	)
+	header := texts.searchHeader
+	if semantic {
+		result, err = b.embedSvc.Search(searchQuery, query)
+		header = texts.semanticHeader
+	} else {
+		result, err = b.searchSvc.Search(searchQuery)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if result.Total == 0 {
+		return fmt.Sprintf(texts.searchNothing, utils.EscapeHTML(query)), nil, nil
+	}
+
+	loc := b.settingsSvc.Location(chatID)
+	pages := (result.Total + searchPageSize - 1) / searchPageSize
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf(header, utils.EscapeHTML(query), result.Total))
+	for i, found := range result.Results {
+		text.WriteString(searchResultText(page*searchPageSize+i+1, found, loc))
+	}
+	if pages > 1 {
+		text.WriteString(fmt.Sprintf(texts.searchPage, page+1, pages))
+	}
+
+	return text.String(), searchMarkup(page, pages), nil
+}
+
+// searchResultText один результат поиска: автор, дата, ссылка на сообщение и отрывок с подсветкой
+func searchResultText(n int, found services.SearchResult, loc *time.Location) string {
+	name := found.FirstName
+	if name == "" {
+		name = found.Username
+	}
+
+	header := fmt.Sprintf("%d. <b>%s</b>, %s", n, utils.EscapeHTML(name), found.Timestamp.In(loc).Format("02.01.2006 15:04"))
+	if link := utils.MessageLink(found.ChatID, found.ChatUsername, found.TelegramMessageID); link != "" {
+		header += fmt.Sprintf(" <a href=\"%s\">↗</a>", link)
+	}
+
+	snippet := strings.Join(strings.Fields(found.Snippet), " ")
+	snippet = strings.NewReplacer(
+		services.SnippetMatchStart, "<b>",
+		services.SnippetMatchEnd, "</b>",
+	).Replace(utils.EscapeHTML(snippet))
+
+	return fmt.Sprintf("\n%s\n%s\n", header, snippet)
+}
+
+// searchMarkup кнопки "назад" и "дальше"; nil, если страница одна
+func searchMarkup(page, pages int) *telebot.ReplyMarkup {
+	if pages <= 1 {
+		return nil
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	var row []telebot.Btn
+	if page > 0 {
+		row = append(row, markup.Data("⬅️", searchBtnPage, strconv.Itoa(page-1)))
+	}
+	if page < pages-1 {
+		row = append(row, markup.Data("➡️", searchBtnPage, strconv.Itoa(page+1)))
+	}
+	markup.Inline(markup.Row(row...))
+	return markup
+}
@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"summarybot/internal/database"
+	"summarybot/internal/utils"
+
+	"gopkg.in/telebot.v3"
+	"gorm.io/gorm"
+)
+
+// sendHTML отправляет HTML-сообщение, предварительно почистив разметку под Telegram.
+// Длинный текст режется на части, которые уходят цепочкой ответов друг на друга,
+// клавиатура из opts прикрепляется к последней части.
+// Возвращает последнее отправленное сообщение
+func (b *Bot) sendHTML(to telebot.Recipient, text string, opts *telebot.SendOptions) (*telebot.Message, error) {
+	if opts == nil {
+		opts = &telebot.SendOptions{}
+	}
+	return b.sendHTMLParts(to, splitMessage(utils.SanitizeTelegramHTML(text), maxMessageLength), opts)
+}
+
+// sendHTMLParts отправляет уже нарезанные части цепочкой ответов
+func (b *Bot) sendHTMLParts(to telebot.Recipient, parts []string, opts *telebot.SendOptions) (*telebot.Message, error) {
+	var last *telebot.Message
+	partOpts := *opts
+	// Ответ в форуме должен остаться в теме исходного сообщения
+	if partOpts.ThreadID == 0 {
+		partOpts.ThreadID = messageTopic(opts.ReplyTo)
+	}
+	for i, part := range parts {
+		partOpts.ReplyMarkup = nil
+		if i == len(parts)-1 {
+			partOpts.ReplyMarkup = opts.ReplyMarkup
+		}
+
+		msg, err := b.sendHTMLPart(to, part, &partOpts)
+		if err != nil {
+			return last, err
+		}
+		last = msg
+		partOpts.ReplyTo = msg
+	}
+
+	return last, nil
+}
+
+// sendHTMLEditable отправляет сообщение, которое потом правится кнопками на месте через editHTML.
+// Клавиатура из opts висит на первой части, а продолжения длинного текста запоминаются,
+// чтобы при правке переписать их, а не слать новые.
+// Возвращает первое сообщение - то, что с кнопками
+func (b *Bot) sendHTMLEditable(to telebot.Recipient, text string, opts *telebot.SendOptions) (*telebot.Message, error) {
+	if opts == nil {
+		opts = &telebot.SendOptions{}
+	}
+	parts := splitMessage(utils.SanitizeTelegramHTML(text), maxMessageLength)
+
+	first, err := b.sendHTMLParts(to, parts[:1], opts)
+	if err != nil || len(parts) == 1 {
+		return first, err
+	}
+
+	var ids []int
+	prev := first
+	for _, part := range parts[1:] {
+		msg, err := b.sendHTMLPart(to, part, &telebot.SendOptions{ReplyTo: prev, ThreadID: messageTopic(first)})
+		if err != nil {
+			b.saveContinuation(first, ids)
+			return first, err
+		}
+		ids = append(ids, msg.ID)
+		prev = msg
+	}
+
+	b.saveContinuation(first, ids)
+	return first, nil
+}
+
+// editHTML заменяет текст сообщения msg, отправленного через sendHTMLEditable. Клавиатура
+// остается на msg, запомненные продолжения переписываются, лишние удаляются, недостающие
+// досылаются ответами - так повторные нажатия не плодят в чате новые части
+func (b *Bot) editHTML(msg *telebot.Message, text string, markup *telebot.ReplyMarkup) error {
+	parts := splitMessage(utils.SanitizeTelegramHTML(text), maxMessageLength)
+
+	if err := b.editHTMLPart(msg, parts[0], markup); err != nil {
+		return err
+	}
+
+	old := b.continuation(msg)
+	ids := make([]int, 0, len(parts)-1)
+	prev := msg
+	for i, part := range parts[1:] {
+		if i < len(old) {
+			stale := &telebot.Message{ID: old[i], Chat: msg.Chat}
+			if err := b.editHTMLPart(stale, part, nil); err == nil {
+				ids = append(ids, stale.ID)
+				prev = stale
+				continue
+			}
+			// Часть удалили руками - вместо нее пришлем новую
+		}
+
+		sent, err := b.sendHTMLPart(msg.Chat, part, &telebot.SendOptions{ReplyTo: prev, ThreadID: messageTopic(msg)})
+		if err != nil {
+			b.saveContinuation(msg, ids)
+			return err
+		}
+		ids = append(ids, sent.ID)
+		prev = sent
+	}
+
+	for _, id := range old {
+		if !slices.Contains(ids, id) {
+			b.telebot.Delete(&telebot.Message{ID: id, Chat: msg.Chat})
+		}
+	}
+
+	b.saveContinuation(msg, ids)
+	return nil
+}
+
+// editHTMLPart правит одно сообщение. Если Telegram не разобрал разметку - правит простым текстом
+func (b *Bot) editHTMLPart(msg *telebot.Message, text string, markup *telebot.ReplyMarkup) error {
+	opts := &telebot.SendOptions{ParseMode: telebot.ModeHTML, ReplyMarkup: markup}
+
+	_, err := b.telebot.Edit(msg, text, opts)
+	if err != nil && isMarkupError(err) {
+		log.Printf("Telegram не принял HTML при редактировании (%v), правим простым текстом", err)
+		opts.ParseMode = telebot.ModeDefault
+		_, err = b.telebot.Edit(msg, utils.StripHTML(text), opts)
+	}
+	if errors.Is(err, telebot.ErrSameMessageContent) || errors.Is(err, telebot.ErrMessageNotModified) {
+		return nil
+	}
+	return err
+}
+
+// continuation продолжения сообщения msg, запомненные sendHTMLEditable, по порядку
+func (b *Bot) continuation(msg *telebot.Message) []int {
+	var ids []int
+	b.db.Model(&database.MessageContinuation{}).
+		Where("chat_id = ? AND message_id = ?", msg.Chat.ID, msg.ID).
+		Order("position").
+		Pluck("part_id", &ids)
+	return ids
+}
+
+// saveContinuation запоминает продолжения сообщения msg вместо прежних
+func (b *Bot) saveContinuation(msg *telebot.Message, ids []int) {
+	err := b.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("chat_id = ? AND message_id = ?", msg.Chat.ID, msg.ID).
+			Delete(&database.MessageContinuation{}).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		rows := make([]database.MessageContinuation, 0, len(ids))
+		for i, id := range ids {
+			rows = append(rows, database.MessageContinuation{ChatID: msg.Chat.ID, MessageID: msg.ID, PartID: id, Position: i + 1})
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения продолжений сообщения %d в чате %d: %v", msg.ID, msg.Chat.ID, err)
+	}
+}
+
+// sendHTMLPart отправляет одну часть сообщения. Если Telegram не разобрал
+// разметку - повторяет отправку простым текстом
+func (b *Bot) sendHTMLPart(to telebot.Recipient, text string, opts *telebot.SendOptions) (*telebot.Message, error) {
+	htmlOpts := *opts
+	htmlOpts.ParseMode = telebot.ModeHTML
+
+	msg, err := b.telebot.Send(to, text, &htmlOpts)
+	if err == nil || !isMarkupError(err) {
+		return msg, err
+	}
+
+	log.Printf("Telegram не принял HTML (%v), отправляем простым текстом", err)
+
+	plainOpts := *opts
+	plainOpts.ParseMode = telebot.ModeDefault
+	return b.telebot.Send(to, utils.StripHTML(text), &plainOpts)
+}
+
+// replyHTML отвечает на текущее сообщение через sendHTML
+func (b *Bot) replyHTML(c telebot.Context, text string) error {
+	_, err := b.sendHTML(c.Chat(), text, &telebot.SendOptions{
+		ReplyTo: c.Message(),
+	})
+	return err
+}
+
+// isMarkupError ошибка Telegram из-за кривой разметки
+func isMarkupError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "can't parse entities") ||
+		strings.Contains(msg, "unsupported start tag") ||
+		strings.Contains(msg, "can't find end tag")
+}
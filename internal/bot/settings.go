@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"summarybot/internal/services"
+	"summarybot/internal/utils"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// HandleTimezone обработчик команды /timezone
+func (b *Bot) HandleTimezone(c telebot.Context) error {
+	if c.Chat().ID > 0 {
+		return c.Reply("⌛ Настройки доступны только в групповых чатах!")
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	args := strings.Fields(c.Message().Text)
+	if len(args) < 2 {
+		loc := b.settingsSvc.Location(c.Chat().ID)
+		return c.Reply(fmt.Sprintf("🕰 Таймзона чата: <code>%s</code> (сейчас %s)\n\n"+
+			"Сменить: <code>/timezone Europe/Moscow</code>",
+			utils.EscapeHTML(loc.String()), time.Now().In(loc).Format("15:04")), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+
+	if !b.IsChatAdmin(c.Chat(), c.Sender()) {
+		return c.Reply("⌛ Менять настройки могут только админы чата.")
+	}
+
+	loc, err := b.settingsSvc.SetTimezone(c.Chat().ID, args[1])
+	if err != nil {
+		return c.Reply("⌛ Не знаю такую таймзону. Пример: <code>/timezone Europe/Moscow</code>", &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+
+	return c.Reply(fmt.Sprintf("✅ Таймзона чата: <code>%s</code> (сейчас %s)",
+		utils.EscapeHTML(loc.String()), time.Now().In(loc).Format("15:04")), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+}
+
+// HandleDigest обработчик команды /digest - настройки ежедневного дайджеста
+func (b *Bot) HandleDigest(c telebot.Context) error {
+	if c.Chat().ID > 0 {
+		return c.Reply("⌛ Настройки доступны только в групповых чатах!")
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	args := strings.Fields(c.Message().Text)
+	if len(args) < 2 {
+		return c.Reply(b.digestStatusText(c.Chat().ID), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+
+	if !b.IsChatAdmin(c.Chat(), c.Sender()) {
+		return c.Reply("⌛ Менять настройки могут только админы чата.")
+	}
+
+	var err error
+	switch arg := strings.ToLower(args[1]); arg {
+	case "on", "вкл":
+		err = b.settingsSvc.SetDigestEnabled(c.Chat().ID, true)
+	case "off", "выкл":
+		err = b.settingsSvc.SetDigestEnabled(c.Chat().ID, false)
+	default:
+		if err = b.settingsSvc.SetDigestTime(c.Chat().ID, arg); err != nil {
+			return c.Reply("⌛ Использование: <code>/digest on|off</code> или <code>/digest 09:00</code>", &telebot.SendOptions{
+				ParseMode: telebot.ModeHTML,
+			})
+		}
+	}
+
+	if err != nil {
+		return c.Reply("⌛ Не получилось сохранить настройки 😞")
+	}
+
+	return c.Reply("✅ Сохранено!\n\n"+b.digestStatusText(c.Chat().ID), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+}
+
+// digestStatusText описывает текущие настройки дайджеста чата
+func (b *Bot) digestStatusText(chatID int64) string {
+	settings := b.settingsSvc.Get(chatID)
+	hour, minute := b.settingsSvc.DigestTime(settings)
+	loc := b.settingsSvc.Location(chatID)
+
+	status := "выключен 🔕"
+	if b.settingsSvc.DigestEnabled(settings) {
+		status = "включен 🔔"
+	}
+
+	return fmt.Sprintf("🌅 <b>Ежедневный дайджест</b> %s\n"+
+		"Время: <code>%02d:%02d</code> (%s)\n\n"+
+		"• <code>/digest on</code> / <code>/digest off</code> - включить/выключить\n"+
+		"• <code>/digest 09:00</code> - время публикации",
+		status, hour, minute, utils.EscapeHTML(loc.String()))
+}
+
+// HandleStyle обработчик команды /style - стиль резюме в чате
+func (b *Bot) HandleStyle(c telebot.Context) error {
+	if c.Chat().ID > 0 {
+		return c.Reply("⌛ Настройки доступны только в групповых чатах!")
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	args := strings.Fields(c.Message().Text)
+	if len(args) < 2 {
+		return c.Reply(b.styleStatusText(c.Chat().ID), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+
+	if !b.IsChatAdmin(c.Chat(), c.Sender()) {
+		return c.Reply("⌛ Менять настройки могут только админы чата.")
+	}
+
+	style, ok := services.ParseSummaryStyle(args[1])
+	if !ok {
+		return c.Reply("⌛ Не знаю такой стиль.\n\n"+b.styleStatusText(c.Chat().ID), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+
+	if err := b.settingsSvc.SetSummaryStyle(c.Chat().ID, style); err != nil {
+		return c.Reply("⌛ Не получилось сохранить настройки 😞")
+	}
+
+	return c.Reply(fmt.Sprintf("✅ Теперь резюме в стиле: <b>%s</b>", style.Title()), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+}
+
+// styleStatusText описывает текущий стиль резюме и доступные варианты
+func (b *Bot) styleStatusText(chatID int64) string {
+	current := b.settingsSvc.SummaryStyle(chatID)
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("✍️ <b>Стиль резюме:</b> %s\n\nДоступные стили:\n", current.Title()))
+	for _, style := range services.SummaryStyles {
+		text.WriteString(fmt.Sprintf("• <code>/style %s</code> - %s\n", style, style.Title()))
+	}
+	return text.String()
+}
+
+// HandleLanguage обработчик команды /language - язык резюме в чате
+func (b *Bot) HandleLanguage(c telebot.Context) error {
+	if c.Chat().ID > 0 {
+		return c.Reply("⌛ Настройки доступны только в групповых чатах!")
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return b.handleUnauthorizedChat(c)
+	}
+
+	args := strings.Fields(c.Message().Text)
+	if len(args) < 2 {
+		return c.Reply(b.languageStatusText(c.Chat().ID), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+
+	if !b.IsChatAdmin(c.Chat(), c.Sender()) {
+		return c.Reply("⌛ Менять настройки могут только админы чата.")
+	}
+
+	lang, ok := services.ParseLanguage(args[1])
+	if !ok {
+		return c.Reply("⌛ Не знаю такой язык.\n\n"+b.languageStatusText(c.Chat().ID), &telebot.SendOptions{
+			ParseMode: telebot.ModeHTML,
+		})
+	}
+
+	if err := b.settingsSvc.SetLanguage(c.Chat().ID, lang); err != nil {
+		return c.Reply("⌛ Не получилось сохранить настройки 😞")
+	}
+
+	return c.Reply(fmt.Sprintf("✅ Язык резюме: <b>%s</b>", lang.Title()), &telebot.SendOptions{
+		ParseMode: telebot.ModeHTML,
+	})
+}
+
+// languageStatusText описывает текущий язык резюме и доступные варианты
+func (b *Bot) languageStatusText(chatID int64) string {
+	current := b.settingsSvc.Language(chatID)
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🌍 <b>Язык резюме:</b> %s\n\nДоступные языки:\n", current.Title()))
+	for _, lang := range services.Languages {
+		text.WriteString(fmt.Sprintf("• <code>/language %s</code> - %s\n", lang, lang.Title()))
+	}
+	text.WriteString("\nДля одного запроса можно попросить прямо в сообщении: <i>что было сегодня in english</i>")
+	return text.String()
+}
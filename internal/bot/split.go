@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// maxMessageLength лимит Telegram на длину сообщения
+	maxMessageLength = 4096
+	// splitReserve запас под закрывающие и заново открытые теги на стыке частей
+	splitReserve = 200
+)
+
+// splitTagRe открывающий или закрывающий тег в уже почищенном HTML
+var splitTagRe = regexp.MustCompile(`<(/?)([a-z][a-z0-9-]*)[^<>]*>`)
+
+// splitMessage режет HTML на части не длиннее limit символов (в UTF-16, как считает Telegram).
+// Режет по абзацам, потом по строкам (пунктам списка), потом по словам и только в крайнем
+// случае посреди слова; теги и HTML-сущности не разрываются, открытые теги закрываются
+// в конце части и открываются заново в начале следующей
+func splitMessage(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if utf16Len(text) <= limit {
+		return []string{text}
+	}
+
+	var parts []string
+	for text != "" {
+		if utf16Len(text) <= limit {
+			parts = append(parts, text)
+			break
+		}
+
+		cut := splitPoint(text, limit-splitReserve)
+		part, rest := text[:cut], text[cut:]
+
+		open := openTags(part)
+		for i := len(open) - 1; i >= 0; i-- {
+			part += "</" + open[i].name + ">"
+		}
+
+		var reopen strings.Builder
+		for _, tag := range open {
+			reopen.WriteString(tag.raw)
+		}
+
+		parts = append(parts, strings.TrimSpace(part))
+		text = reopen.String() + strings.TrimLeft(rest, " \n")
+	}
+
+	return parts
+}
+
+// splitPoint находит место разреза не дальше limit символов от начала
+func splitPoint(text string, limit int) int {
+	// Байтовая позиция, до которой помещается limit символов
+	maxIdx, units := 0, 0
+	for i, r := range text {
+		units += utf16.RuneLen(r)
+		if units > limit {
+			break
+		}
+		maxIdx = i + utf8.RuneLen(r)
+	}
+
+	head := text[:maxIdx]
+	minIdx := maxIdx / 3 // слишком короткие части не нужны
+
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if idx := strings.LastIndex(head, sep); idx > minIdx && safeCut(text, idx) {
+			return idx
+		}
+	}
+
+	// Режем посреди слова, но не посреди тега или сущности
+	idx := maxIdx
+	for idx > 0 && !safeCut(text, idx) {
+		idx--
+	}
+	if idx == 0 {
+		return maxIdx
+	}
+	return idx
+}
+
+// safeCut можно ли резать текст в позиции idx: не внутри тега и не внутри сущности
+func safeCut(text string, idx int) bool {
+	if !utf8.RuneStart(text[idx]) {
+		return false
+	}
+
+	head := text[:idx]
+	if strings.LastIndex(head, "<") > strings.LastIndex(head, ">") {
+		return false
+	}
+
+	amp := strings.LastIndex(head, "&")
+	return amp < 0 || strings.LastIndex(head, ";") > amp || idx-amp > 10
+}
+
+// openTag тег, оставшийся открытым к концу части
+type openTag struct {
+	name string
+	raw  string
+}
+
+// openTags возвращает теги, открытые к концу куска HTML
+func openTags(html string) []openTag {
+	var stack []openTag
+	for _, m := range splitTagRe.FindAllStringSubmatch(html, -1) {
+		if m[1] == "" {
+			stack = append(stack, openTag{name: m[2], raw: m[0]})
+			continue
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].name == m[2] {
+				stack = stack[:i]
+				break
+			}
+		}
+	}
+	return stack
+}
+
+// utf16Len длина строки в UTF-16 - так длину сообщения считает Telegram
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
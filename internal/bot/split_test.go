@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitMessageShort(t *testing.T) {
+	tests := []string{
+		"",
+		"привет",
+		"<b>жирный</b> текст",
+		strings.Repeat("я", maxMessageLength),
+	}
+
+	for _, text := range tests {
+		parts := splitMessage(text, maxMessageLength)
+		if len(parts) != 1 || parts[0] != text {
+			t.Errorf("splitMessage(%d символов) = %d частей, want исходный текст одной частью", len(text), len(parts))
+		}
+	}
+}
+
+func TestSplitMessageLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"латиница", strings.Repeat("word ", 3000)},
+		{"кириллица", strings.Repeat("слово ", 3000)},
+		{"эмодзи", strings.Repeat("😀🎉 ", 3000)},
+		{"эмодзи без пробелов", strings.Repeat("😀", 5000)},
+		{"одно длинное слово", strings.Repeat("a", 10000)},
+		{"сущности", strings.Repeat("a &amp; b &lt; c ", 1000)},
+		{"теги", strings.Repeat("<b>жирный</b> <i>курсив 😀</i> ", 800)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitMessage(tt.text, maxMessageLength)
+			if len(parts) < 2 {
+				t.Fatalf("got %d parts, want split", len(parts))
+			}
+			for i, part := range parts {
+				if n := utf16Len(part); n > maxMessageLength {
+					t.Errorf("part %d: %d UTF-16 units, limit %d", i, n, maxMessageLength)
+				}
+				if strings.Contains(part, "�") {
+					t.Errorf("part %d: broken rune", i)
+				}
+				if amp := strings.LastIndex(part, "&"); amp >= 0 && !strings.Contains(part[amp:], ";") {
+					t.Errorf("part %d: entity cut at the end", i)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitMessageTags(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		tags []string
+	}{
+		{"жирный", "<b>" + strings.Repeat("очень длинный текст ", 400) + "</b>", []string{"<b>"}},
+		{"вложенные", "<b><i>" + strings.Repeat("очень длинный текст ", 400) + "</i></b>", []string{"<b>", "<i>"}},
+		{"pre и code", `<pre><code class="language-go">` + strings.Repeat("x := compute(1)\n", 600) + "</code></pre>",
+			[]string{"<pre>", `<code class="language-go">`}},
+		{"ссылка", `<a href="https://example.com">` + strings.Repeat("ссылка ", 1200) + "</a>", []string{`<a href="https://example.com">`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitMessage(tt.text, maxMessageLength)
+			if len(parts) < 2 {
+				t.Fatalf("got %d parts, want split", len(parts))
+			}
+			for i, part := range parts {
+				if open := openTags(part); len(open) != 0 {
+					t.Errorf("part %d: tags left open: %v", i, open)
+				}
+				if i == 0 {
+					continue
+				}
+				want := strings.Join(tt.tags, "")
+				if !strings.HasPrefix(part, want) {
+					t.Errorf("part %d starts with %q, want reopened %q", i, part[:min(len(part), 60)], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitMessageBoundaries(t *testing.T) {
+	paragraph := strings.Repeat("слово ", 150) + "конец."
+	paragraphs := strings.Repeat(paragraph+"\n\n", 10)
+
+	for i, part := range splitMessage(paragraphs, maxMessageLength) {
+		if !strings.HasSuffix(part, "конец.") {
+			t.Errorf("part %d ends with %q, want paragraph boundary", i, part[max(0, len(part)-30):])
+		}
+	}
+
+	bullet := "• " + strings.Repeat("пункт ", 40) + "точка"
+	bullets := strings.Repeat(bullet+"\n", 40)
+
+	for i, part := range splitMessage(bullets, maxMessageLength) {
+		if !strings.HasPrefix(part, "• ") || !strings.HasSuffix(part, "точка") {
+			t.Errorf("part %d is not cut on a bullet boundary", i)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"summarybot/internal/services"
+	"summarybot/internal/utils"
+
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	summaryBtnRegenerate = "sum_regen"
+	summaryBtnMore       = "sum_more"
+	summaryBtnShorter    = "sum_short"
+	summaryBtnStats      = "sum_stats"
+	summaryBtnBack       = "sum_back"
+
+	// maxSummaryDetail насколько можно подробнее/короче относительно обычного резюме
+	maxSummaryDetail = 1
+	// statsTopAuthors сколько самых активных участников показывать в статистике
+	statsTopAuthors = 5
+)
+
+// SummaryButtons кнопки под резюме, для регистрации обработчика
+var SummaryButtons = []*telebot.Btn{
+	{Unique: summaryBtnRegenerate},
+	{Unique: summaryBtnMore},
+	{Unique: summaryBtnShorter},
+	{Unique: summaryBtnStats},
+	{Unique: summaryBtnBack},
+}
+
+// summaryMarkup клавиатура под резюме; nil, если резюме не сохранено
+func summaryMarkup(summaryID uint, lang services.Language) *telebot.ReplyMarkup {
+	if summaryID == 0 {
+		return nil
+	}
+
+	texts := textsFor(lang)
+	id := strconv.FormatUint(uint64(summaryID), 10)
+
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(
+			markup.Data(texts.btnRegenerate, summaryBtnRegenerate, id),
+			markup.Data(texts.btnStats, summaryBtnStats, id),
+		),
+		markup.Row(
+			markup.Data(texts.btnMore, summaryBtnMore, id),
+			markup.Data(texts.btnShorter, summaryBtnShorter, id),
+		),
+	)
+	return markup
+}
+
+// replySummary отвечает резюме с кнопками под ним
+func (b *Bot) replySummary(c telebot.Context, text string, summaryID uint, lang services.Language) error {
+	_, err := b.sendHTMLEditable(c.Chat(), text, &telebot.SendOptions{
+		ReplyTo:     c.Message(),
+		ReplyMarkup: summaryMarkup(summaryID, lang),
+	})
+	return err
+}
+
+// HandleSummaryButton обработчик кнопок под резюме: пересобрать, подробнее, короче, статистика.
+// Резюме и его период берутся из сохраненной записи, сообщение правится на месте
+func (b *Bot) HandleSummaryButton(c telebot.Context) error {
+	cb := c.Callback()
+	if cb == nil || cb.Message == nil {
+		return nil
+	}
+
+	if !b.IsChatAllowed(c.Chat().ID) {
+		return c.Respond()
+	}
+
+	id, err := strconv.ParseUint(cb.Data, 10, 64)
+	if err != nil {
+		return c.Respond()
+	}
+
+	stored, req, err := b.summarySvc.StoredSummary(uint(id), b.settingsSvc.Location(c.Chat().ID))
+	if err != nil || stored.ChatID != c.Chat().ID {
+		return c.Respond(&telebot.CallbackResponse{Text: textsFor(b.settingsSvc.Language(c.Chat().ID)).notFound})
+	}
+
+	texts := textsFor(req.Language)
+
+	switch cb.Unique {
+	case summaryBtnStats:
+		c.Respond()
+		return b.editHTML(cb.Message, b.summaryStatsText(req), backMarkup(stored.ID, req.Language))
+	case summaryBtnBack:
+		c.Respond()
+		return b.editHTML(cb.Message, summaryMessageText(req, stored.Summary, int64(stored.MessageCount)),
+			summaryMarkup(stored.ID, req.Language))
+	case summaryBtnRegenerate:
+		if !b.IsChatAdmin(c.Chat(), c.Sender()) {
+			return c.Respond(&telebot.CallbackResponse{Text: texts.adminsOnly, ShowAlert: true})
+		}
+		req.Force = true
+	case summaryBtnMore:
+		if req.Detail >= maxSummaryDetail {
+			return c.Respond(&telebot.CallbackResponse{Text: texts.maxDetail})
+		}
+		req.Detail++
+	case summaryBtnShorter:
+		if req.Detail <= -maxSummaryDetail {
+			return c.Respond(&telebot.CallbackResponse{Text: texts.minDetail})
+		}
+		req.Detail--
+	default:
+		return c.Respond()
+	}
+
+	c.Respond(&telebot.CallbackResponse{Text: texts.generating})
+
+	result, err := b.summarySvc.GenerateSummary(req)
+	if err != nil {
+		log.Printf("Ошибка пересборки резюме %d: %v", stored.ID, err)
+		return b.editHTML(cb.Message, texts.failed, summaryMarkup(stored.ID, req.Language))
+	}
+
+	count := b.summarySvc.CountMessages(req)
+	return b.editHTML(cb.Message, summaryMessageText(req, result.Text, count), summaryMarkup(result.ID, req.Language))
+}
+
+// summaryMessageText собирает сообщение с резюме так же, как его отправляют обработчики запросов
+func summaryMessageText(req services.SummaryRequest, summary string, count int64) string {
+	texts := textsFor(req.Language)
+	switch {
+	case req.ThreadRootID != 0:
+		return fmt.Sprintf(texts.threadHeader, summary, count)
+	case req.UserID != 0:
+		return fmt.Sprintf(texts.participantHeader, utils.EscapeHTML(req.UserName), req.Period.NameIn(req.Language), summary, count)
+	case req.Period.Kind == services.PeriodSince:
+		return fmt.Sprintf(texts.catchUpHeader, req.Period.Start.Format("02.01 15:04"), summary, count, "")
+	default:
+		return fmt.Sprintf(texts.header, req.Period.NameIn(req.Language), summary, count)
+	}
+}
+
+// summaryStatsText статистика сообщений за период резюме
+func (b *Bot) summaryStatsText(req services.SummaryRequest) string {
+	texts := textsFor(req.Language)
+	var stats services.PeriodStats
+	if req.ThreadRootID != 0 || req.TopicID != 0 {
+		messages, _ := b.summarySvc.Messages(req)
+		stats = services.MessagesStats(messages, req.Period.Location(), statsTopAuthors)
+	} else {
+		stats = b.statsSvc.GetPeriodStats(req.ChatID, req.UserID, req.Period.Start, req.Period.End, statsTopAuthors)
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf(texts.statsHeader, req.Period.NameIn(req.Language)))
+	text.WriteString(fmt.Sprintf(texts.statsMessages, stats.Messages))
+	if req.UserID == 0 {
+		text.WriteString(fmt.Sprintf(texts.statsParticipants, stats.Participants))
+	}
+	if stats.BusiestHour >= 0 {
+		text.WriteString(fmt.Sprintf(texts.statsBusiestHour, stats.BusiestHour, (stats.BusiestHour+1)%24))
+	}
+
+	if req.UserID == 0 && len(stats.TopAuthors) > 0 {
+		text.WriteString(texts.statsTop)
+		for i, author := range stats.TopAuthors {
+			name := author.FirstName
+			if name == "" {
+				name = author.Username
+			}
+			text.WriteString(fmt.Sprintf("%d. %s - %d\n", i+1, utils.EscapeHTML(name), author.Count))
+		}
+	}
+
+	return text.String()
+}
+
+// backMarkup клавиатура с одной кнопкой возврата к резюме
+func backMarkup(summaryID uint, lang services.Language) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(textsFor(lang).btnBack, summaryBtnBack, strconv.FormatUint(uint64(summaryID), 10)),
+	))
+	return markup
+}
@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"fmt"
+	"summarybot/internal/services"
+	"summarybot/internal/utils"
+)
+
+// summaryBotTexts тексты бота вокруг резюме
+type summaryBotTexts struct {
+	groupOnly         string
+	usage             string
+	tooLong           string // максимум дней
+	generating        string
+	catchingUp        string
+	recalling         string
+	unknownUser       string
+	failed            string
+	header            string // период, резюме, число сообщений
+	catchUpHeader     string // начало, резюме, число сообщений, примечание
+	catchUpCapped     func(hours int) string
+	participantHeader string // имя, период, резюме, число сообщений
+	digestHeader      string // период, дата, резюме, число сообщений
+
+	// резюме ветки ответов
+	threadUsage    string
+	readingThread  string
+	threadNotFound string
+	threadHeader   string // резюме, число сообщений
+
+	// кнопки под резюме
+	btnRegenerate string
+	btnMore       string
+	btnShorter    string
+	btnStats      string
+	btnBack       string
+	adminsOnly    string
+	maxDetail     string
+	minDetail     string
+	notFound      string
+
+	// выгрузка файлом
+	exporting     string
+	exportTitle   string // период
+	exportCaption string // период, число сообщений
+	exportEmpty   string // период
+
+	// подборка ссылок
+	collectingLinks string
+	linksHeader     string // период, число ссылок
+	linksEmpty      string // период
+
+	// вопросы по истории
+	searchingHistory string
+	qaNothing        string
+	qaSources        string
+
+	// поиск
+	searchUsage    string
+	searchNothing  string // запрос
+	searchHeader   string // запрос, сколько нашлось
+	searchPage     string // страница, всего страниц
+	searchExpired  string
+	semanticHeader string // запрос, сколько нашлось
+	semanticOff    string
+
+	// статистика по кнопке
+	statsHeader       string // период
+	statsMessages     string // число сообщений
+	statsParticipants string // число участников
+	statsBusiestHour  string // час начала, час конца
+	statsTop          string
+}
+
+var botTexts = map[services.Language]summaryBotTexts{
+	services.LangRU: {
+		groupOnly: "⌛ Summary доступен только в групповых чатах, братан! 🤖",
+		usage: "Напиши '@zagichak_bot что было за сегодня/вчера/позавчера', " +
+			"'@zagichak_bot что было за N дней' (макс 7), 'за последние 3 часа', 'с понедельника' " +
+			"или '@zagichak_bot что было 10.10 - 12.10'",
+		tooLong:       "Могу показать резюме максимум за %d дней 📅",
+		generating:    "Генерирую резюме... ⏳",
+		catchingUp:    "Смотрю, что ты пропустил... ⏳",
+		recalling:     "Вспоминаю, что он писал... ⏳",
+		unknownUser:   "Не понял про кого ты, братан 🤔 Напиши '@zagichak_bot что писал @username за неделю'",
+		failed:        "Ошибка при создании резюме 😞",
+		header:        "📋 <b>Резюме за %s</b>\n\n%s\n\n<i>Проанализировано сообщений: %d</i>",
+		catchUpHeader: "👀 <b>Что ты пропустил (с %s)</b>\n\n%s\n\n<i>Проанализировано сообщений: %d</i>%s",
+		catchUpCapped: func(hours int) string {
+			return fmt.Sprintf("\n<i>Ты давно не писал, поэтому показываю только последние %d %s</i>",
+				hours, utils.Pluralize(hours, "час", "часа", "часов"))
+		},
+		participantHeader: "🗣 <b>Что писал %s за %s</b>\n\n%s\n\n<i>Проанализировано сообщений: %d</i>",
+		digestHeader:      "🌅 <b>Дайджест за %s (%s)</b>\n\n%s\n\n<i>Проанализировано сообщений: %d</i>",
+
+		threadUsage:    "Ответь на любое сообщение ветки с '@zagichak_bot саммари треда' - и я перескажу, о чем там спорили 🧵",
+		readingThread:  "Читаю ветку... ⏳",
+		threadNotFound: "Не нашел эту ветку - я вижу только сообщения, написанные после моего добавления 🤷‍♂️",
+		threadHeader:   "🧵 <b>Резюме треда</b>\n\n%s\n\n<i>Проанализировано сообщений: %d</i>",
+
+		btnRegenerate: "🔄 Заново",
+		btnMore:       "➕ Подробнее",
+		btnShorter:    "➖ Короче",
+		btnStats:      "📊 Статистика",
+		btnBack:       "⬅️ К резюме",
+		adminsOnly:    "Пересобрать резюме могут только админы чата",
+		maxDetail:     "Подробнее уже некуда 🤷‍♂️",
+		minDetail:     "Короче уже некуда 🤷‍♂️",
+		notFound:      "Это резюме уже не найти 😞",
+
+		exporting:     "Собираю файл... ⏳",
+		exportTitle:   "Резюме чата за %s",
+		exportCaption: "📦 Резюме и переписка за %s (сообщений: %d)",
+		exportEmpty:   "За %s нечего выгружать - сообщений нет 🤷‍♂️",
+
+		collectingLinks: "Собираю ссылки... ⏳",
+		linksHeader:     "🔗 <b>Ссылки за %s</b> (%d)\n",
+		linksEmpty:      "За %s ссылок не кидали 🤷‍♂️",
+
+		searchingHistory: "Роюсь в истории... ⏳",
+		qaNothing:        "Ничего про это в истории не нашел 🤷‍♂️",
+		qaSources:        "<i>Где это было:</i>",
+
+		searchUsage:    "Напиши, что искать: <code>/search шашлыки на даче</code> 🔎",
+		searchNothing:  "По запросу «%s» ничего не нашел 🤷‍♂️",
+		searchHeader:   "🔎 <b>Поиск: «%s»</b> - нашел %d\n",
+		searchPage:     "\n<i>Страница %d из %d</i>",
+		searchExpired:  "Этот поиск уже не полистать, поищи заново",
+		semanticHeader: "🧠 <b>Похожее по смыслу: «%s»</b> - нашел %d\n",
+		semanticOff:    "Поиск по смыслу тут не включен, ищу по словам: <code>/search запрос</code>",
+
+		statsHeader:       "📊 <b>Статистика за %s</b>\n\n",
+		statsMessages:     "💬 Сообщений: %d\n",
+		statsParticipants: "👥 Участников: %d\n",
+		statsBusiestHour:  "🔥 Самый активный час: %02d:00–%02d:00\n",
+		statsTop:          "\n<b>Больше всех писали:</b>\n",
+	},
+	services.LangEN: {
+		groupOnly: "⌛ Summaries are only available in group chats! 🤖",
+		usage: "Try '@zagichak_bot summary for today/yesterday', '@zagichak_bot summary for the last 3 days' (max 7), " +
+			"'last 2 hours', 'since monday' or '@zagichak_bot summary 10.10 - 12.10'",
+		tooLong:       "I can summarize at most %d days 📅",
+		generating:    "Generating the summary... ⏳",
+		catchingUp:    "Checking what you missed... ⏳",
+		recalling:     "Recalling what they wrote... ⏳",
+		unknownUser:   "Who do you mean? 🤔 Try '@zagichak_bot что писал @username за неделю in english'",
+		failed:        "Failed to create the summary 😞",
+		header:        "📋 <b>Summary for %s</b>\n\n%s\n\n<i>Messages analyzed: %d</i>",
+		catchUpHeader: "👀 <b>What you missed (since %s)</b>\n\n%s\n\n<i>Messages analyzed: %d</i>%s",
+		catchUpCapped: func(hours int) string {
+			return fmt.Sprintf("\n<i>You haven't written for a while, so I'm only showing the last %d hours</i>", hours)
+		},
+		participantHeader: "🗣 <b>What %s wrote for %s</b>\n\n%s\n\n<i>Messages analyzed: %d</i>",
+		digestHeader:      "🌅 <b>Digest for %s (%s)</b>\n\n%s\n\n<i>Messages analyzed: %d</i>",
+
+		threadUsage:    "Reply to any message of the thread with '@zagichak_bot thread summary' and I'll tell you what it was about 🧵",
+		readingThread:  "Reading the thread... ⏳",
+		threadNotFound: "Couldn't find this thread - I only see messages written after I was added 🤷‍♂️",
+		threadHeader:   "🧵 <b>Thread summary</b>\n\n%s\n\n<i>Messages analyzed: %d</i>",
+
+		btnRegenerate: "🔄 Regenerate",
+		btnMore:       "➕ More detail",
+		btnShorter:    "➖ Shorter",
+		btnStats:      "📊 Stats",
+		btnBack:       "⬅️ Back to summary",
+		adminsOnly:    "Only chat admins can regenerate the summary",
+		maxDetail:     "That's as detailed as it gets 🤷‍♂️",
+		minDetail:     "That's as short as it gets 🤷‍♂️",
+		notFound:      "This summary can't be found anymore 😞",
+
+		exporting:     "Putting the file together... ⏳",
+		exportTitle:   "Chat summary for %s",
+		exportCaption: "📦 Summary and transcript for %s (messages: %d)",
+		exportEmpty:   "Nothing to export for %s - no messages 🤷‍♂️",
+
+		collectingLinks: "Collecting links... ⏳",
+		linksHeader:     "🔗 <b>Links for %s</b> (%d)\n",
+		linksEmpty:      "No links were shared for %s 🤷‍♂️",
+
+		searchingHistory: "Digging through the history... ⏳",
+		qaNothing:        "Couldn't find anything about that in the history 🤷‍♂️",
+		qaSources:        "<i>Where it was:</i>",
+
+		searchUsage:    "Tell me what to look for: <code>/search bbq at the dacha</code> 🔎",
+		searchNothing:  "Nothing found for «%s» 🤷‍♂️",
+		searchHeader:   "🔎 <b>Search: «%s»</b> - %d found\n",
+		searchPage:     "\n<i>Page %d of %d</i>",
+		searchExpired:  "Can't page through this search anymore, search again",
+		semanticHeader: "🧠 <b>Similar in meaning: «%s»</b> - %d found\n",
+		semanticOff:    "Semantic search isn't enabled here, search by words: <code>/search query</code>",
+
+		statsHeader:       "📊 <b>Stats for %s</b>\n\n",
+		statsMessages:     "💬 Messages: %d\n",
+		statsParticipants: "👥 Participants: %d\n",
+		statsBusiestHour:  "🔥 Busiest hour: %02d:00–%02d:00\n",
+		statsTop:          "\n<b>Most active:</b>\n",
+	},
+}
+
+// textsFor тексты на языке lang
+func textsFor(lang services.Language) summaryBotTexts {
+	return botTexts[lang.OrDefault()]
+}
+
+// summaryLanguage язык резюме: из запроса ("summary in english"), иначе из настроек чата
+func (b *Bot) summaryLanguage(chatID int64, text string) services.Language {
+	if lang, ok := services.ParseLanguage(utils.RequestedLanguage(text)); ok {
+		return lang
+	}
+	return b.settingsSvc.Language(chatID)
+}
@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"log"
+
+	"gopkg.in/telebot.v3"
+)
+
+// messageTopic тема форума, в которой написано сообщение; 0 - обычный чат или общая тема
+func messageTopic(m *telebot.Message) int {
+	if m == nil || !m.TopicMessage {
+		return 0
+	}
+	return m.ThreadID
+}
+
+// topicOptions опции отправки в ту же тему форума, где написано сообщение m
+func topicOptions(m *telebot.Message) *telebot.SendOptions {
+	return &telebot.SendOptions{ThreadID: messageTopic(m)}
+}
+
+// HandleTopicChange обработчик создания и переименования темы форума - запоминаем название для резюме
+func (b *Bot) HandleTopicChange(c telebot.Context) error {
+	m := c.Message()
+	if m == nil || !b.IsChatAllowed(c.Chat().ID) {
+		return nil
+	}
+
+	topic := m.TopicCreated
+	if topic == nil {
+		topic = m.TopicEdited
+	}
+	if topic == nil {
+		return nil
+	}
+
+	if err := b.summarySvc.SaveTopicName(c.Chat().ID, m.ThreadID, topic.Name); err != nil {
+		log.Printf("Ошибка сохранения названия темы %d в чате %d: %v", m.ThreadID, c.Chat().ID, err)
+	}
+	return nil
+}
@@ -19,6 +19,13 @@ type Config struct {
 	OpenAIModel      string
 	MaxTokens        int
 	MinMessagesForAI int
+	DefaultTimezone  string
+	DigestEnabled    bool
+	DigestTime       string
+	// эмбеддинги для поиска по смыслу; EmbeddingsBaseURL пустой - тот же адрес, что и OpenAIBaseURL
+	EmbeddingsEnabled bool
+	EmbeddingsBaseURL string
+	EmbeddingModel    string
 }
 
 func Load() *Config {
@@ -37,18 +44,24 @@ func Load() *Config {
 	}
 
 	return &Config{
-		TelegramToken:    getEnv("TELEGRAM_BOT_TOKEN", ""),
-		OpenAIAPIKey:     getEnv("OPENAI_API_KEY", ""),
-		OpenAIBaseURL:    getEnv("OPENAI_BASE_URL", "http://31.172.78.152:9000/v1"),
-		DatabasePath:     getEnv("DATABASE_PATH", "./summarybot.db"),
-		Port:             getEnv("PORT", "8080"),
-		BotUsername:      getEnv("BOT_USERNAME", "zagichak_bot"),
-		AllowedChats:     parseInt64List(getEnv("ALLOWED_CHATS", "")),
-		AdminUserIDs:     parseInt64List(getEnv("ADMIN_USER_IDS", "")),
-		RequireApproval:  getEnv("REQUIRE_APPROVAL", "true") == "true",
-		OpenAIModel:      getEnv("OPENAI_MODEL", "gpt-4o-mini"),
-		MaxTokens:        maxTokens,
-		MinMessagesForAI: minMessages,
+		TelegramToken:     getEnv("TELEGRAM_BOT_TOKEN", ""),
+		OpenAIAPIKey:      getEnv("OPENAI_API_KEY", ""),
+		OpenAIBaseURL:     getEnv("OPENAI_BASE_URL", "http://31.172.78.152:9000/v1"),
+		DatabasePath:      getEnv("DATABASE_PATH", "./summarybot.db"),
+		Port:              getEnv("PORT", "8080"),
+		BotUsername:       getEnv("BOT_USERNAME", "zagichak_bot"),
+		AllowedChats:      parseInt64List(getEnv("ALLOWED_CHATS", "")),
+		AdminUserIDs:      parseInt64List(getEnv("ADMIN_USER_IDS", "")),
+		RequireApproval:   getEnv("REQUIRE_APPROVAL", "true") == "true",
+		OpenAIModel:       getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+		MaxTokens:         maxTokens,
+		MinMessagesForAI:  minMessages,
+		DefaultTimezone:   getEnv("TIMEZONE", "Europe/Moscow"),
+		DigestEnabled:     getEnv("DIGEST_ENABLED", "false") == "true",
+		DigestTime:        getEnv("DIGEST_TIME", "09:00"),
+		EmbeddingsEnabled: getEnv("EMBEDDINGS_ENABLED", "false") == "true",
+		EmbeddingsBaseURL: getEnv("EMBEDDINGS_BASE_URL", ""),
+		EmbeddingModel:    getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
 	}
 }
 
@@ -5,22 +5,72 @@ import (
 )
 
 type Message struct {
-	ID        uint  `gorm:"primaryKey"`
-	ChatID    int64 `gorm:"index"`
-	UserID    int64 `gorm:"index"`
-	Username  string
-	FirstName string
-	Text      string    `gorm:"type:text"`
-	Timestamp time.Time `gorm:"index"`
-	CreatedAt time.Time
+	ID                uint  `gorm:"primaryKey"`
+	ChatID            int64 `gorm:"index;index:idx_messages_chat_tg_id,priority:1"`
+	TelegramMessageID int   `gorm:"index:idx_messages_chat_tg_id,priority:2"`
+	ReplyToMessageID  int   `gorm:"index"` // TelegramMessageID сообщения, на которое это ответ
+	TopicID           int   `gorm:"index"` // message_thread_id темы форума, 0 - обычный чат или общая тема
+	ChatUsername      string
+	UserID            int64 `gorm:"index"`
+	Username          string
+	FirstName         string
+	Text              string    `gorm:"type:text"` // текст или его замена: подпись к медиа, опрос, эмодзи стикера, файл, место
+	ContentType       string    // Content*; пустой - текст (сообщения, сохраненные до появления типов)
+	ForwardedFrom     string    // от кого переслано, пустое - не пересланное
+	Timestamp         time.Time `gorm:"index"`
+	CreatedAt         time.Time
+}
+
+// Типы содержимого сообщения
+const (
+	ContentText      = "text"
+	ContentPhoto     = "photo"
+	ContentVideo     = "video"
+	ContentAnimation = "animation"
+	ContentVideoNote = "video_note"
+	ContentVoice     = "voice"
+	ContentAudio     = "audio"
+	ContentDocument  = "document"
+	ContentSticker   = "sticker"
+	ContentPoll      = "poll"
+	ContentLocation  = "location"
+	ContentVenue     = "venue"
+	ContentContact   = "contact"
+)
+
+// MessageEdit прежний текст отредактированного сообщения; в Message всегда лежит последняя версия
+type MessageEdit struct {
+	ID                uint  `gorm:"primaryKey"`
+	MessageID         uint  `gorm:"index"` // Message.ID
+	ChatID            int64 `gorm:"index"`
+	TelegramMessageID int
+	Text              string    `gorm:"type:text"` // текст до правки
+	EditedAt          time.Time // когда сообщение поправили
+	CreatedAt         time.Time
 }
 
 type ChatSummary struct {
-	ID        uint      `gorm:"primaryKey"`
-	ChatID    int64     `gorm:"index"`
-	Date      time.Time `gorm:"index"`
-	Summary   string    `gorm:"type:text"`
-	CreatedAt time.Time
+	ID             uint  `gorm:"primaryKey"`
+	ChatID         int64 `gorm:"index"`
+	UserID         int64 `gorm:"index"`
+	UserName       string
+	Date           time.Time `gorm:"index"`
+	PeriodKind     string    `gorm:"index"`
+	PeriodEnd      time.Time
+	PeriodDays     int
+	PeriodHours    int
+	PeriodExplicit bool
+	ThreadRootID   int // корень ветки ответов для резюме треда
+	TopicID        int // тема форума, 0 - весь чат
+	MessageCount   int
+	Model          string
+	Style          string
+	Language       string
+	Detail         int
+	LastMessageAt  time.Time
+	Summary        string `gorm:"type:text"`
+	Structured     string `gorm:"type:text"`
+	CreatedAt      time.Time
 }
 
 type AllowedChat struct {
@@ -79,3 +129,74 @@ type UsedGreeting struct {
 	UsedAt    time.Time `gorm:"index"`
 	CreatedAt time.Time
 }
+
+// ChatSettings хранит настройки конкретного чата
+type ChatSettings struct {
+	ID            uint  `gorm:"primaryKey"`
+	ChatID        int64 `gorm:"uniqueIndex"`
+	Timezone      string
+	DigestEnabled *bool // nil - как в конфиге
+	DigestTime    string
+	DigestSentAt  time.Time
+	SummaryStyle  string
+	Language      string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// ForumTopic название темы форума - Telegram присылает его только при создании и переименовании темы
+type ForumTopic struct {
+	ID        uint  `gorm:"primaryKey"`
+	ChatID    int64 `gorm:"uniqueIndex:idx_forum_topics_chat_topic"`
+	TopicID   int   `gorm:"uniqueIndex:idx_forum_topics_chat_topic"`
+	Name      string
+	UpdatedAt time.Time
+}
+
+// SharedLink ссылка из сообщения чата, вынутая при сохранении сообщения
+type SharedLink struct {
+	ID                uint  `gorm:"primaryKey"`
+	ChatID            int64 `gorm:"index"`
+	TopicID           int
+	TelegramMessageID int
+	UserID            int64
+	Username          string
+	FirstName         string
+	URL               string `gorm:"type:text"`
+	Domain            string
+	Description       string // описание от модели, пустое - еще не описывали
+	DescriptionLang   string
+	Timestamp         time.Time `gorm:"index"`
+	CreatedAt         time.Time
+}
+
+// MessageEmbedding вектор сообщения для поиска по смыслу (float32, нормированный, little-endian)
+type MessageEmbedding struct {
+	ID        uint  `gorm:"primaryKey"`
+	MessageID uint  `gorm:"uniqueIndex"`
+	ChatID    int64 `gorm:"index"`
+	TopicID   int
+	Timestamp time.Time
+	Model     string
+	Vector    []byte
+	CreatedAt time.Time
+}
+
+// MessageContinuation продолжение длинного сообщения бота с кнопками: кнопки висят на первой части
+// (MessageID), а остальные части правятся вместе с ней, когда кнопку нажимают
+type MessageContinuation struct {
+	ID        uint  `gorm:"primaryKey"`
+	ChatID    int64 `gorm:"index:idx_continuations_chat_msg,priority:1"`
+	MessageID int   `gorm:"index:idx_continuations_chat_msg,priority:2"`
+	PartID    int   // ID сообщения с очередной частью
+	Position  int   // номер части после MessageID, с 1
+	CreatedAt time.Time
+}
+
+// JobCursor докуда дошла фоновая задача - чтобы после перезапуска продолжить с того же места
+type JobCursor struct {
+	ID            uint   `gorm:"primaryKey"`
+	Name          string `gorm:"uniqueIndex"`
+	LastMessageID uint
+	UpdatedAt     time.Time
+}
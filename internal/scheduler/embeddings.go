@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"log"
+	"summarybot/internal/services"
+	"time"
+)
+
+const (
+	// embeddingIdleInterval как часто проверяем новые сообщения, когда все уже посчитано
+	embeddingIdleInterval = time.Minute
+	// embeddingRetryInterval пауза после ошибки API, чтобы не долбить его
+	embeddingRetryInterval = 5 * time.Minute
+)
+
+// EmbeddingJob фоновая задача: досчитывает эмбеддинги сообщений, которых еще нет в индексе.
+// Докуда дошли, хранится в БД, так что после перезапуска задача продолжает с того же места
+type EmbeddingJob struct {
+	embeddingSvc *services.EmbeddingService
+}
+
+func NewEmbeddingJob(embeddingSvc *services.EmbeddingService) *EmbeddingJob {
+	return &EmbeddingJob{embeddingSvc: embeddingSvc}
+}
+
+// Start крутит задачу; блокирует, запускать в горутине
+func (j *EmbeddingJob) Start() {
+	log.Printf("Фоновый расчет эмбеддингов запущен")
+
+	total := 0
+	for {
+		n, err := j.embeddingSvc.Backfill()
+		switch {
+		case err != nil:
+			log.Printf("Ошибка расчета эмбеддингов: %v", err)
+			time.Sleep(embeddingRetryInterval)
+		case n == 0:
+			if total > 0 {
+				log.Printf("Эмбеддинги посчитаны, просмотрено сообщений: %d", total)
+				total = 0
+			}
+			time.Sleep(embeddingIdleInterval)
+		default:
+			total += n
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"log"
+	"summarybot/internal/services"
+	"time"
+)
+
+const (
+	// tickInterval как часто проверяем, не пора ли слать дайджесты
+	tickInterval = time.Minute
+	// digestWindow сколько после назначенного времени дайджест еще можно отправить,
+	// чтобы после долгого простоя бот не слал вчерашние дайджесты посреди ночи
+	digestWindow = time.Hour
+)
+
+// DigestSender отправляет ежедневный дайджест в чат
+type DigestSender interface {
+	AllowedChatIDs() []int64
+	SendDailyDigest(chatID int64) error
+}
+
+// Scheduler запускает периодические задачи бота
+type Scheduler struct {
+	sender      DigestSender
+	settingsSvc *services.SettingsService
+}
+
+func New(sender DigestSender, settingsSvc *services.SettingsService) *Scheduler {
+	return &Scheduler{
+		sender:      sender,
+		settingsSvc: settingsSvc,
+	}
+}
+
+// Start крутит планировщик; блокирует, запускать в горутине
+func (s *Scheduler) Start() {
+	log.Printf("Планировщик запущен")
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	s.tick(time.Now())
+	for now := range ticker.C {
+		s.tick(now)
+	}
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	for _, chatID := range s.sender.AllowedChatIDs() {
+		if !s.digestDue(chatID, now) {
+			continue
+		}
+
+		// Отмечаем до отправки: если генерация упала, не долбим чат каждую минуту
+		if err := s.settingsSvc.MarkDigestSent(chatID, now); err != nil {
+			log.Printf("Ошибка сохранения отметки дайджеста для чата %d: %v", chatID, err)
+			continue
+		}
+
+		if err := s.sender.SendDailyDigest(chatID); err != nil {
+			log.Printf("Ошибка отправки дайджеста в чат %d: %v", chatID, err)
+		}
+	}
+}
+
+// digestDue пора ли отправлять дайджест в чат
+func (s *Scheduler) digestDue(chatID int64, now time.Time) bool {
+	settings := s.settingsSvc.Get(chatID)
+	if !s.settingsSvc.DigestEnabled(settings) {
+		return false
+	}
+
+	loc := s.settingsSvc.Location(chatID)
+	local := now.In(loc)
+	hour, minute := s.settingsSvc.DigestTime(settings)
+	due := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+
+	if local.Before(due) || local.Sub(due) > digestWindow {
+		return false
+	}
+
+	// Сегодня уже отправляли
+	return settings.DigestSentAt.IsZero() || settings.DigestSentAt.Before(due)
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultContextWindow контекст модели, если она нам неизвестна (прокси, локальные модели)
+	defaultContextWindow = 8192
+	// promptReserveTokens запас под системный промпт и обвязку запроса
+	promptReserveTokens = 2000
+	// maxChunkTokens верхняя граница куска - на огромных кусках модели теряют детали
+	maxChunkTokens = 24000
+	// minChunkTokens нижняя граница, чтобы не дробить переписку на крошки
+	minChunkTokens = 1000
+	// charsPerToken грубая оценка: для кириллицы токен - примерно 3 символа
+	charsPerToken = 3
+	// maxReduceRounds защита от бесконечного схлопывания частичных резюме
+	maxReduceRounds = 3
+)
+
+// modelContextWindows размер контекста известных моделей по префиксу имени
+var modelContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4.1", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 128000},
+	{"o3", 128000},
+	{"o4", 128000},
+}
+
+// contextWindow возвращает размер контекста для модели
+func contextWindow(model string) int {
+	model = strings.ToLower(model)
+	for _, m := range modelContextWindows {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// estimateTokens грубо оценивает количество токенов в тексте
+func estimateTokens(text string) int {
+	return utf8.RuneCountInString(text)/charsPerToken + 1
+}
+
+// chunkTokenBudget сколько токенов переписки помещается в один запрос
+func (s *SummaryService) chunkTokenBudget() int {
+	budget := contextWindow(s.model) - s.maxTokens - promptReserveTokens
+	if budget > maxChunkTokens {
+		budget = maxChunkTokens
+	}
+	if budget < minChunkTokens {
+		budget = minChunkTokens
+	}
+	return budget
+}
+
+// splitByTokenBudget режет строки на куски, каждый из которых влезает в бюджет
+func splitByTokenBudget(lines []string, budget int) [][]string {
+	var chunks [][]string
+	var current []string
+	currentTokens := 0
+
+	for _, line := range lines {
+		tokens := estimateTokens(line)
+		if tokens > budget {
+			// Одна простыня больше бюджета - обрезаем, иначе она не влезет никуда
+			runes := []rune(line)
+			line = string(runes[:(budget-1)*charsPerToken]) + "…\n"
+			tokens = estimateTokens(line)
+		}
+		if currentTokens+tokens > budget && len(current) > 0 {
+			chunks = append(chunks, current)
+			current = nil
+			currentTokens = 0
+		}
+		current = append(current, line)
+		currentTokens += tokens
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// summarizeTranscript делает резюме переписки, при необходимости по схеме map-reduce:
+// режет переписку на куски, резюмирует каждый и затем сводит частичные резюме в итоговое
+func (s *SummaryService) summarizeTranscript(lines []string, prompt summaryPrompt, count int) (string, error) {
+	budget := s.chunkTokenBudget()
+	chunks := splitByTokenBudget(lines, budget)
+
+	if len(chunks) <= 1 {
+		return s.generateAISummary(strings.Join(lines, ""), prompt, count)
+	}
+
+	log.Printf("Переписка не влезает в контекст: %d сообщений, %d кусков по ~%d токенов",
+		count, len(chunks), budget)
+
+	partials := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		partial, err := s.summarizeChunk(strings.Join(chunk, ""), i+1, len(chunks))
+		if err != nil {
+			return "", fmt.Errorf("резюме куска %d/%d: %w", i+1, len(chunks), err)
+		}
+		partials = append(partials, partial)
+	}
+
+	// Если частичные резюме сами не влезают - схлопываем их еще раз
+	for round := 0; round < maxReduceRounds; round++ {
+		groups := splitByTokenBudget(withSeparators(partials), budget)
+		if len(groups) <= 1 {
+			break
+		}
+
+		reduced := make([]string, 0, len(groups))
+		for i, group := range groups {
+			partial, err := s.summarizeChunk(strings.Join(group, ""), i+1, len(groups))
+			if err != nil {
+				return "", fmt.Errorf("схлопывание частичных резюме: %w", err)
+			}
+			reduced = append(reduced, partial)
+		}
+		partials = reduced
+	}
+
+	return s.mergePartialSummaries(partials, prompt, count)
+}
+
+// summarizeChunk делает сжатую выжимку одного куска переписки
+func (s *SummaryService) summarizeChunk(text string, part, total int) (string, error) {
+	systemPrompt := `Ты помогаешь делать резюме большого чата. Тебе дают ОДИН кусок переписки (или набор выжимок).
+
+Сделай сжатую выжимку этого куска:
+- Перечисли все заметные темы и события списком, по одной строке на тему
+- Для каждой темы укажи, кто участвовал, и к чему пришли
+- Сохрани все ссылки, договоренности и важные факты дословно
+- Если у сообщений есть номера вида [#1234 15:04], в конце строки темы укажи номер первого сообщения темы в формате [#1234]
+- Если в тексте уже есть метки [#1234], сохраняй их как есть
+- Пиши ТОЛЬКО то, что реально есть в тексте, ничего не выдумывай
+- Без HTML и без вступлений, обычный текст`
+
+	userPrompt := fmt.Sprintf("Кусок %d из %d:\n\n%s", part, total, text)
+
+	return s.complete(systemPrompt, userPrompt, s.maxTokens)
+}
+
+// mergePartialSummaries сводит частичные резюме в итоговое в обычном формате
+func (s *SummaryService) mergePartialSummaries(partials []string, prompt summaryPrompt, count int) (string, error) {
+	userPrompt := fmt.Sprintf(`%s
+
+Переписка была слишком большой, поэтому ее разбили на части и по каждой сделали выжимку.
+Собери из выжимок ниже ОДИН итоговый ответ в нужном формате JSON.
+
+ВАЖНО: Используй ТОЛЬКО информацию из выжимок, объединяй одинаковые темы, не повторяйся!
+
+Всего сообщений в переписке: %d
+
+Выжимки по частям:
+%s`, prompt.task, count, strings.Join(withSeparators(partials), ""))
+
+	return s.completeJSON(prompt.system, userPrompt, s.maxTokens)
+}
+
+// withSeparators оформляет частичные резюме как пронумерованные блоки
+func withSeparators(partials []string) []string {
+	blocks := make([]string, 0, len(partials))
+	for i, p := range partials {
+		blocks = append(blocks, fmt.Sprintf("--- Часть %d ---\n%s\n\n", i+1, strings.TrimSpace(p)))
+	}
+	return blocks
+}
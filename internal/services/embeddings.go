@@ -0,0 +1,360 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"summarybot/internal/database"
+	"summarybot/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// embeddingBatchSize сколько сообщений отправляем в /embeddings за один запрос
+	embeddingBatchSize = 64
+	// minEmbeddingRunes короче этого ("ок", "ахах") искать по смыслу нечего
+	minEmbeddingRunes = 12
+	// maxEmbeddingRunes длинные сообщения обрезаем, чтобы не упереться в лимит модели
+	maxEmbeddingRunes = 2000
+	// semanticMinScore ниже этой косинусной близости сообщение считаем непохожим
+	semanticMinScore = 0.3
+	// semanticMaxResults сколько самых похожих сообщений вообще показываем
+	semanticMaxResults = 50
+	// embeddingScanBatch по сколько векторов читаем из БД при поиске
+	embeddingScanBatch = 2000
+	// embeddingTimeout сколько ждем ответа /embeddings
+	embeddingTimeout = time.Minute
+)
+
+// EmbeddingService считает эмбеддинги сообщений через OpenAI-совместимый /embeddings
+// и ищет по ним похожие по смыслу сообщения. Без адреса или модели выключен.
+// Запросы шлем сами: go-openai этой версии не пропускает модели, которых нет в его списке
+type EmbeddingService struct {
+	db      *gorm.DB
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+func NewEmbeddingService(db *gorm.DB, baseURL, apiKey, model string) *EmbeddingService {
+	return &EmbeddingService{
+		db:      db,
+		client:  &http.Client{Timeout: embeddingTimeout},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+	}
+}
+
+// embeddingRequest и embeddingResponse - тело запроса и ответа /embeddings
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Enabled включен ли поиск по смыслу
+func (s *EmbeddingService) Enabled() bool {
+	return s != nil && s.baseURL != "" && s.model != ""
+}
+
+// cursorName имя курсора дозаполнения; у каждой модели свой, чтобы после смены модели пересчитать все заново
+func (s *EmbeddingService) cursorName() string {
+	return "embeddings:" + s.model
+}
+
+// Backfill считает эмбеддинги следующей пачки сообщений после сохраненного курсора и сдвигает курсор.
+// Возвращает, сколько сообщений просмотрено; 0 - все уже посчитано.
+// Если API недоступно, курсор не двигается и пачка будет посчитана в следующий раз.
+// Если API отверг пачку из-за содержимого, она считается по одному сообщению, а отвергнутые
+// сообщения пропускаются - иначе одно негодное сообщение навсегда остановило бы индексацию
+func (s *EmbeddingService) Backfill() (int, error) {
+	cursor, err := s.cursor()
+	if err != nil {
+		return 0, err
+	}
+
+	var messages []database.Message
+	err = s.db.Where("id > ?", cursor.LastMessageID).
+		Order("id").
+		Limit(embeddingBatchSize).
+		Find(&messages).Error
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	// Сеть, 5xx, ключ или лимиты - курсор не трогаем, пачка посчитается в следующий раз
+	done, err := len(messages), s.saveEmbeddings(messages)
+	if isInputRejected(err) {
+		done, err = s.saveEmbeddingsEach(messages)
+	} else if err != nil {
+		return 0, err
+	}
+	if done == 0 {
+		return 0, err
+	}
+
+	cursor.Name = s.cursorName()
+	cursor.LastMessageID = messages[done-1].ID
+	cursor.UpdatedAt = time.Now()
+	if err := s.db.Save(&cursor).Error; err != nil {
+		return 0, err
+	}
+	return done, err
+}
+
+// saveEmbeddingsEach считает эмбеддинги по одному сообщению, пропуская те, что API отверг.
+// Возвращает, сколько сообщений с начала списка обработано до первой ошибки API или сети
+func (s *EmbeddingService) saveEmbeddingsEach(messages []database.Message) (int, error) {
+	for i, msg := range messages {
+		err := s.saveEmbeddings([]database.Message{msg})
+		if isInputRejected(err) {
+			log.Printf("Эмбеддинг сообщения %d пропущен: %v", msg.ID, err)
+			continue
+		}
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(messages), nil
+}
+
+// Refresh пересчитывает эмбеддинг сообщения после правки. Сообщения, до которых фоновая задача
+// еще не дошла, не трогает - она посчитает их сама
+func (s *EmbeddingService) Refresh(msg database.Message) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	cursor, err := s.cursor()
+	if err != nil || msg.ID > cursor.LastMessageID {
+		return err
+	}
+
+	// Если после правки сообщение стало слишком коротким, старый вектор просто пропадет
+	if err := s.db.Where("message_id = ?", msg.ID).Delete(&database.MessageEmbedding{}).Error; err != nil {
+		return err
+	}
+	return s.saveEmbeddings([]database.Message{msg})
+}
+
+// cursor докуда дошло дозаполнение эмбеддингов текущей модели
+func (s *EmbeddingService) cursor() (database.JobCursor, error) {
+	var cursor database.JobCursor
+	err := s.db.Where("name = ?", s.cursorName()).Limit(1).Find(&cursor).Error
+	return cursor, err
+}
+
+// saveEmbeddings считает и сохраняет векторы сообщений; слишком короткие пропускает
+func (s *EmbeddingService) saveEmbeddings(messages []database.Message) error {
+	var (
+		todo   []database.Message
+		inputs []string
+	)
+	for _, msg := range messages {
+		text := strings.Join(strings.Fields(msg.Text), " ")
+		runes := []rune(text)
+		if len(runes) < minEmbeddingRunes {
+			continue
+		}
+		if len(runes) > maxEmbeddingRunes {
+			text = string(runes[:maxEmbeddingRunes])
+		}
+		todo = append(todo, msg)
+		inputs = append(inputs, text)
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	vectors, err := s.embed(inputs)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uint, 0, len(todo))
+	rows := make([]database.MessageEmbedding, 0, len(todo))
+	for i, msg := range todo {
+		ids = append(ids, msg.ID)
+		rows = append(rows, database.MessageEmbedding{
+			MessageID: msg.ID,
+			ChatID:    msg.ChatID,
+			TopicID:   msg.TopicID,
+			Timestamp: msg.Timestamp,
+			Model:     s.model,
+			Vector:    utils.EncodeVector(vectors[i]),
+		})
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("message_id IN ?", ids).Delete(&database.MessageEmbedding{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// embed нормированные эмбеддинги текстов в том же порядке
+func (s *EmbeddingService) embed(texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: s.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, s.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	httpResp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(httpResp.Body, 512))
+		return nil, &embeddingStatusError{status: httpResp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+
+	var resp embeddingResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("не разобрали ответ /embeddings: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("эмбеддинг с неожиданным индексом %d", item.Index)
+		}
+		vectors[item.Index] = utils.NormalizeVector(item.Embedding)
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("нет эмбеддинга для текста %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// embeddingStatusError /embeddings ответил не 200
+type embeddingStatusError struct {
+	status int
+	body   string
+}
+
+func (e *embeddingStatusError) Error() string {
+	return fmt.Sprintf("/embeddings ответил %d: %s", e.status, e.body)
+}
+
+// isInputRejected API отверг сами тексты (4xx): повтор того же запроса не поможет.
+// Ошибки ключа, адреса и лимитов запросов к текстам отношения не имеют - их просто повторяем позже
+func isInputRejected(err error) bool {
+	var statusErr *embeddingStatusError
+	if !errors.As(err, &statusErr) || statusErr.status < 400 || statusErr.status >= 500 {
+		return false
+	}
+	switch statusErr.status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+		http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
+
+// Search ищет сообщения, близкие по смыслу к text, с теми же ограничениями по чату, теме и времени,
+// что и полнотекстовый поиск (Terms и MatchAny не используются). Самые похожие - первыми
+func (s *EmbeddingService) Search(q SearchQuery, text string) (SearchPage, error) {
+	if !s.Enabled() || strings.TrimSpace(text) == "" {
+		return SearchPage{}, nil
+	}
+
+	vectors, err := s.embed([]string{text})
+	if err != nil {
+		return SearchPage{}, err
+	}
+	query := vectors[0]
+
+	scope := s.db.Model(&database.MessageEmbedding{}).
+		Select("id", "message_id", "vector").
+		Where("chat_id = ? AND model = ?", q.ChatID, s.model)
+	if q.TopicID != 0 {
+		scope = scope.Where("topic_id = ?", q.TopicID)
+	}
+	if !q.Start.IsZero() {
+		scope = scope.Where("timestamp >= ? AND timestamp < ?", q.Start.UTC(), q.End.UTC())
+	}
+
+	type scored struct {
+		messageID uint
+		score     float32
+	}
+	var found []scored
+	var batch []database.MessageEmbedding
+	err = scope.FindInBatches(&batch, embeddingScanBatch, func(tx *gorm.DB, _ int) error {
+		for _, row := range batch {
+			if score := utils.DotProduct(query, utils.DecodeVector(row.Vector)); score >= semanticMinScore {
+				found = append(found, scored{row.MessageID, score})
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return SearchPage{}, err
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].score > found[j].score
+	})
+	if len(found) > semanticMaxResults {
+		found = found[:semanticMaxResults]
+	}
+
+	page := SearchPage{Total: len(found)}
+	if q.Offset >= len(found) {
+		return page, nil
+	}
+	found = found[q.Offset:]
+	if q.Limit > 0 && len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+
+	ids := make([]uint, 0, len(found))
+	for _, f := range found {
+		ids = append(ids, f.messageID)
+	}
+	var messages []database.Message
+	if err := s.db.Where("id IN ?", ids).Find(&messages).Error; err != nil {
+		return SearchPage{}, err
+	}
+	byID := make(map[uint]database.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+
+	for _, f := range found {
+		if msg, ok := byID[f.messageID]; ok {
+			page.Results = append(page.Results, SearchResult{Message: msg, Snippet: scanSnippet(msg.Text)})
+		}
+	}
+	return page, nil
+}
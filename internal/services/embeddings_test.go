@@ -0,0 +1,105 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"summarybot/internal/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newEmbeddingTestDB БД в памяти с сообщениями texts
+func newEmbeddingTestDB(t *testing.T, texts ...string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&database.Message{}, &database.MessageEmbedding{}, &database.JobCursor{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, text := range texts {
+		msg := database.Message{ChatID: -100, TelegramMessageID: i + 1, Text: text, Timestamp: time.Now()}
+		if err := db.Create(&msg).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// newEmbeddingStub /embeddings, который отвечает status на все, а при 200 отвергает тексты со словом "ОТКАЗ"
+func newEmbeddingStub(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			http.Error(w, "stub error", status)
+			return
+		}
+
+		var req embeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var resp embeddingResponse
+		for i, input := range req.Input {
+			if strings.Contains(input, "ОТКАЗ") {
+				http.Error(w, "input rejected", http.StatusBadRequest)
+				return
+			}
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Index: i, Embedding: []float32{1, 0}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestBackfillKeepsCursorOnServerError(t *testing.T) {
+	db := newEmbeddingTestDB(t, "первое длинное сообщение", "второе длинное сообщение")
+	svc := NewEmbeddingService(db, newEmbeddingStub(t, http.StatusInternalServerError).URL, "", "test-model")
+
+	n, err := svc.Backfill()
+	if err == nil || n != 0 {
+		t.Fatalf("Backfill() = %d, %v, want 0 and error", n, err)
+	}
+
+	var cursor database.JobCursor
+	db.Where("name = ?", svc.cursorName()).Limit(1).Find(&cursor)
+	if cursor.LastMessageID != 0 {
+		t.Errorf("cursor moved to %d after a server error", cursor.LastMessageID)
+	}
+}
+
+func TestBackfillSkipsRejectedMessage(t *testing.T) {
+	db := newEmbeddingTestDB(t, "первое длинное сообщение", "ОТКАЗ длинное сообщение", "третье длинное сообщение")
+	svc := NewEmbeddingService(db, newEmbeddingStub(t, http.StatusOK).URL, "", "test-model")
+
+	n, err := svc.Backfill()
+	if err != nil || n != 3 {
+		t.Fatalf("Backfill() = %d, %v, want 3 and no error", n, err)
+	}
+
+	var cursor database.JobCursor
+	db.Where("name = ?", svc.cursorName()).Limit(1).Find(&cursor)
+	if cursor.LastMessageID != 3 {
+		t.Errorf("cursor = %d, want 3", cursor.LastMessageID)
+	}
+
+	var ids []uint
+	db.Model(&database.MessageEmbedding{}).Order("message_id").Pluck("message_id", &ids)
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("embedded messages = %v, want [1 3]", ids)
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"summarybot/internal/database"
+	texttemplate "text/template"
+)
+
+// ExportFormat формат файла выгрузки
+type ExportFormat string
+
+const (
+	ExportMarkdown ExportFormat = "md"
+	ExportHTML     ExportFormat = "html"
+)
+
+// SummaryExport готовый файл с резюме и перепиской
+type SummaryExport struct {
+	FileName string
+	MIME     string
+	Content  []byte
+	Messages int
+}
+
+// exportLine одно сообщение переписки в выгрузке
+type exportLine struct {
+	Time   string
+	Author string
+	Text   string
+	Link   string
+}
+
+// exportView данные для шаблонов выгрузки
+type exportView struct {
+	Title       string
+	Summary     string
+	SummaryHTML htmltemplate.HTML
+	L           summaryLabels
+	Lines       []exportLine
+}
+
+var exportMarkdownTemplate = texttemplate.Must(texttemplate.New("export").Parse(
+	`# {{.Title}}
+
+{{.Summary}}
+
+## {{.L.Transcript}}
+
+{{range .Lines}}**[{{.Time}}] {{.Author}}:**{{with .Link}} [↗]({{.}}){{end}}
+{{.Text}}
+
+{{end}}`))
+
+var exportHTMLTemplate = htmltemplate.Must(htmltemplate.New("export").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+.summary { white-space: pre-wrap; background: #f5f5f5; padding: 1em; border-radius: 8px; }
+.msg { margin: 0.6em 0; }
+.meta { color: #888; font-size: 0.9em; }
+.text { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="summary">{{.SummaryHTML}}</div>
+<h2>{{.L.Transcript}}</h2>
+{{range .Lines}}<div class="msg"><span class="meta">[{{.Time}}]{{with .Link}} <a href="{{.}}">↗</a>{{end}}</span> <b>{{.Author}}:</b> <span class="text">{{.Text}}</span></div>
+{{end}}</body>
+</html>
+`))
+
+// ExportSummary собирает документ с резюме и полной перепиской за период запроса.
+// Резюме берется готовое, если оно уже было, иначе генерируется как обычно
+func (s *SummaryService) ExportSummary(req SummaryRequest, format ExportFormat, title string) (*SummaryExport, error) {
+	result, err := s.GenerateSummary(req)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.getMessages(req)
+	if err != nil {
+		return nil, err
+	}
+
+	links := newChatLinkInfo(req.ChatID, messages)
+	view := exportView{
+		Title: title,
+		L:     req.Language.info().labels,
+		Lines: make([]exportLine, 0, len(messages)),
+	}
+
+	for _, msg := range messages {
+		line := exportLine{
+			Time:   msg.Timestamp.In(req.Period.Location()).Format("02.01.2006 15:04"),
+			Author: authorName(msg),
+			Text:   messageText(msg, req.Language),
+		}
+		if msg.TelegramMessageID != 0 {
+			line.Link = links.link(msg.TelegramMessageID)
+		}
+		if format == ExportMarkdown {
+			line.Text = strings.ReplaceAll(line.Text, "\n", "  \n")
+		}
+		view.Lines = append(view.Lines, line)
+	}
+
+	structured := s.storedStructured(result.ID)
+
+	var buf bytes.Buffer
+	export := &SummaryExport{Messages: len(messages)}
+	fileName := fmt.Sprintf("summary_%s_%s",
+		req.Period.Start.Format("2006-01-02"), req.Period.End.Add(-1).Format("2006-01-02"))
+
+	switch format {
+	case ExportHTML:
+		view.SummaryHTML = htmltemplate.HTML(result.Text)
+		if structured == nil {
+			view.SummaryHTML = htmltemplate.HTML(htmltemplate.HTMLEscapeString(result.Text))
+		}
+		err = exportHTMLTemplate.Execute(&buf, view)
+		export.FileName, export.MIME = fileName+".html", "text/html"
+	default:
+		view.Summary = result.Text
+		if structured != nil {
+			if view.Summary, err = structured.RenderMarkdown(req.UserID != 0, req.Language, links); err != nil {
+				return nil, err
+			}
+		}
+		err = exportMarkdownTemplate.Execute(&buf, view)
+		export.FileName, export.MIME = fileName+".md", "text/markdown"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	export.Content = buf.Bytes()
+	return export, nil
+}
+
+// storedStructured достает структурированное резюме из сохраненной записи
+func (s *SummaryService) storedStructured(id uint) *StructuredSummary {
+	if id == 0 {
+		return nil
+	}
+
+	var cs database.ChatSummary
+	if err := s.db.Select("structured").First(&cs, id).Error; err != nil || cs.Structured == "" {
+		return nil
+	}
+
+	var structured StructuredSummary
+	if err := json.Unmarshal([]byte(cs.Structured), &structured); err != nil {
+		return nil
+	}
+	return &structured
+}
+
+// authorName имя автора сообщения для переписки
+func authorName(msg database.Message) string {
+	if msg.FirstName != "" {
+		return msg.FirstName
+	}
+	return msg.Username
+}
+
+// messageText текст сообщения для переписки: медиа, опросы и прочее без текста помечаются типом,
+// пересланное - тем, от кого переслано
+func messageText(msg database.Message, lang Language) string {
+	texts := lang.info().texts
+
+	var marks []string
+	if msg.ForwardedFrom != "" {
+		marks = append(marks, fmt.Sprintf(texts.forwardedFrom, msg.ForwardedFrom))
+	}
+	if label, ok := texts.contentLabels[msg.ContentType]; ok {
+		marks = append(marks, label)
+	}
+	if len(marks) == 0 {
+		return msg.Text
+	}
+	return strings.TrimSpace(fmt.Sprintf("[%s] %s", strings.Join(marks, ", "), msg.Text))
+}
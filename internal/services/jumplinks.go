@@ -0,0 +1,46 @@
+package services
+
+import (
+	"summarybot/internal/database"
+	"summarybot/internal/utils"
+)
+
+// jumpLinksInstruction дописывается к системному промпту, когда в переписке есть ID сообщений
+const jumpLinksInstruction = `
+
+НОМЕРА СООБЩЕНИЙ:
+- Перед каждым сообщением в переписке стоит его номер, например [#1234 15:04] (в выжимках - [#1234])
+- В "message_ids" каждой темы первым ставь номер сообщения, с которого началась тема, дальше - другие ключевые сообщения темы
+- Бери номера ТОЛЬКО из переписки, не придумывай их`
+
+// chatLinkInfo данные чата, нужные для ссылок на сообщения
+type chatLinkInfo struct {
+	chatID       int64
+	chatUsername string
+	// known номера сообщений, которые реально есть в переписке
+	known map[int]bool
+}
+
+// newChatLinkInfo собирает данные для ссылок по сообщениям переписки
+func newChatLinkInfo(chatID int64, messages []database.Message) chatLinkInfo {
+	info := chatLinkInfo{chatID: chatID, known: make(map[int]bool, len(messages))}
+	for _, msg := range messages {
+		if msg.TelegramMessageID != 0 {
+			info.known[msg.TelegramMessageID] = true
+		}
+		if msg.ChatUsername != "" {
+			info.chatUsername = msg.ChatUsername
+		}
+	}
+	return info
+}
+
+// hasRefs есть ли в переписке номера сообщений, на которые можно сослаться
+func (i chatLinkInfo) hasRefs() bool {
+	return len(i.known) > 0 && utils.MessageLink(i.chatID, i.chatUsername, 1) != ""
+}
+
+// link возвращает ссылку на сообщение или пустую строку
+func (i chatLinkInfo) link(messageID int) string {
+	return utils.MessageLink(i.chatID, i.chatUsername, messageID)
+}
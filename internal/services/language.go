@@ -0,0 +1,177 @@
+package services
+
+import (
+	"strings"
+	"summarybot/internal/database"
+)
+
+// Language язык, на котором бот пишет резюме
+type Language string
+
+const (
+	LangRU Language = "ru"
+	LangEN Language = "en"
+)
+
+// DefaultLanguage язык чата, в котором ничего не выбирали
+const DefaultLanguage = LangRU
+
+// Languages все поддерживаемые языки в порядке показа
+var Languages = []Language{LangRU, LangEN}
+
+// languageInfo описание языка: название, синонимы и инструкция для модели
+type languageInfo struct {
+	title   string
+	aliases []string
+	// instruction дописывается к системному промпту резюме
+	instruction string
+	// answerInstruction то же для ответов обычным текстом, не JSON
+	answerInstruction string
+	texts             summaryTexts
+	labels            summaryLabels
+}
+
+// summaryTexts ответы сервиса резюме, которые пишутся без модели
+type summaryTexts struct {
+	noMessages     string // период
+	noUserMessages string // период, имя
+	tooFew         string // период, число сообщений, минимум
+	failed         string
+	generalTopic   string // подпись общей темы форума
+	unnamedTopic   string // ID темы, название которой бот не видел
+	forwardedFrom  string // от кого переслано
+	// contentLabels пометки сообщений без текста в переписке, по database.Content*
+	contentLabels map[string]string
+}
+
+// summaryLabels заголовки разделов в шаблонах резюме
+type summaryLabels struct {
+	Topics      string
+	Participant string
+	Positions   string
+	Decisions   string
+	Links       string
+	Transcript  string
+}
+
+var languages = map[Language]languageInfo{
+	LangRU: {
+		title:   "русский 🇷🇺",
+		aliases: []string{"русский", "russian", "рус"},
+		instruction: `
+
+ЯЗЫК ОТВЕТА: все строки в JSON пиши на русском языке, даже если в переписке другие языки`,
+		answerInstruction: `
+
+ЯЗЫК ОТВЕТА: отвечай на русском языке, даже если в переписке другие языки`,
+		texts: summaryTexts{
+			noMessages:     "За %s никто ничего не писал, братан 🤷‍♂️",
+			noUserMessages: "За %s %s ничего не писал, братан 🤷‍♂️",
+			tooFew: "За %s было всего %d сообщений - слишком мало для нормального резюме, братан 📱\n\n" +
+				"Попробуй запросить резюме когда народ побольше пообщается! (нужно минимум %d сообщений)",
+			failed:        "Не смог замутить резюме, братан 😞",
+			generalTopic:  "Общий",
+			unnamedTopic:  "Тема #%d",
+			forwardedFrom: "переслано от %s",
+			contentLabels: map[string]string{
+				database.ContentPhoto:     "фото",
+				database.ContentVideo:     "видео",
+				database.ContentAnimation: "гифка",
+				database.ContentVideoNote: "кружок",
+				database.ContentVoice:     "голосовое",
+				database.ContentAudio:     "аудио",
+				database.ContentDocument:  "файл",
+				database.ContentSticker:   "стикер",
+				database.ContentPoll:      "опрос",
+				database.ContentLocation:  "геопозиция",
+				database.ContentVenue:     "место",
+				database.ContentContact:   "контакт",
+			},
+		},
+		labels: summaryLabels{
+			Topics:      "Главные темы",
+			Participant: "О чем писал",
+			Positions:   "Позиции и мнения",
+			Decisions:   "Договорились",
+			Links:       "Полезняк",
+			Transcript:  "Переписка",
+		},
+	},
+	LangEN: {
+		title:   "English 🇬🇧",
+		aliases: []string{"english", "английский", "англ", "eng"},
+		instruction: `
+
+ЯЗЫК ОТВЕТА: все строки в JSON (названия тем, описания, решения, позиции, описания ссылок) пиши на английском языке,
+даже если переписка на русском. Стиль сохраняй, но сленг и обороты подбирай естественные для английского.
+Имена участников не переводи`,
+		answerInstruction: `
+
+ЯЗЫК ОТВЕТА: отвечай на английском языке, даже если переписка на русском. Имена участников не переводи`,
+		texts: summaryTexts{
+			noMessages:     "Nobody wrote anything for %s 🤷‍♂️",
+			noUserMessages: "For %s, %s didn't write anything 🤷‍♂️",
+			tooFew: "There were only %[2]d messages for %[1]s - too few for a proper summary 📱\n\n" +
+				"Ask again when people have chatted a bit more! (at least %[3]d messages needed)",
+			failed:        "Couldn't put the summary together 😞",
+			generalTopic:  "General",
+			unnamedTopic:  "Topic #%d",
+			forwardedFrom: "forwarded from %s",
+			contentLabels: map[string]string{
+				database.ContentPhoto:     "photo",
+				database.ContentVideo:     "video",
+				database.ContentAnimation: "GIF",
+				database.ContentVideoNote: "video message",
+				database.ContentVoice:     "voice message",
+				database.ContentAudio:     "audio",
+				database.ContentDocument:  "file",
+				database.ContentSticker:   "sticker",
+				database.ContentPoll:      "poll",
+				database.ContentLocation:  "location",
+				database.ContentVenue:     "place",
+				database.ContentContact:   "contact",
+			},
+		},
+		labels: summaryLabels{
+			Topics:      "Main topics",
+			Participant: "What they wrote about",
+			Positions:   "Positions and opinions",
+			Decisions:   "Agreed on",
+			Links:       "Useful links",
+			Transcript:  "Transcript",
+		},
+	},
+}
+
+// ParseLanguage находит язык по коду или названию
+func ParseLanguage(value string) (Language, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, lang := range Languages {
+		if string(lang) == value {
+			return lang, true
+		}
+		for _, alias := range languages[lang].aliases {
+			if alias == value {
+				return lang, true
+			}
+		}
+	}
+	return "", false
+}
+
+// OrDefault возвращает сам язык или язык по умолчанию, если он пустой или неизвестный
+func (l Language) OrDefault() Language {
+	if _, ok := languages[l]; ok {
+		return l
+	}
+	return DefaultLanguage
+}
+
+func (l Language) info() languageInfo {
+	return languages[l.OrDefault()]
+}
+
+// Title человекочитаемое название языка
+func (l Language) Title() string {
+	return l.info().title
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"fmt"
+	"summarybot/internal/utils"
+	"time"
+)
+
+// PeriodKind тип периода для резюме
+type PeriodKind string
+
+const (
+	// PeriodDay - один календарный день N дней назад (сегодня/вчера/позавчера)
+	PeriodDay PeriodKind = "day"
+	// PeriodRange - диапазон из нескольких дней
+	PeriodRange PeriodKind = "range"
+	// PeriodSince - все сообщения после указанного момента ("что я пропустил")
+	PeriodSince PeriodKind = "since"
+	// PeriodHours - несколько часов: последние N часов или интервал "с 10 до 14"
+	PeriodHours PeriodKind = "hours"
+	// PeriodThread - ветка ответов: от первого до последнего сообщения ветки
+	PeriodThread PeriodKind = "thread"
+)
+
+// Period описывает временное окно, за которое делается резюме
+type Period struct {
+	Kind PeriodKind
+	Days int
+	// Hours - для "последних N часов" число часов, для интервала по часам 0
+	Hours int
+	Start time.Time
+	End   time.Time
+	// Explicit - диапазон задан явными датами, а не "последние N дней"
+	Explicit bool
+}
+
+// DayPeriod возвращает период в один день, daysAgo дней назад, по времени чата loc
+func DayPeriod(daysAgo int, loc *time.Location) Period {
+	start := dayStart(time.Now().In(loc)).AddDate(0, 0, -daysAgo)
+	return Period{
+		Kind:  PeriodDay,
+		Days:  daysAgo,
+		Start: start,
+		End:   start.AddDate(0, 0, 1),
+	}
+}
+
+// LastDaysPeriod возвращает период за последние days дней, включая сегодня, по времени чата loc
+func LastDaysPeriod(days int, loc *time.Location) Period {
+	if days < 1 {
+		days = 1
+	}
+	start := dayStart(time.Now().In(loc)).AddDate(0, 0, -(days - 1))
+	return Period{
+		Kind:  PeriodRange,
+		Days:  days,
+		Start: start,
+		End:   start.AddDate(0, 0, days),
+	}
+}
+
+// DateRangePeriod возвращает период с начала дня from до конца дня to включительно.
+// Границы дней считаются в таймзоне, в которой заданы from и to
+func DateRangePeriod(from, to time.Time) Period {
+	if to.Before(from) {
+		from, to = to, from
+	}
+	start := dayStart(from)
+	end := dayStart(to).AddDate(0, 0, 1)
+	days := 0
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		days++
+	}
+	return Period{
+		Kind:     PeriodRange,
+		Days:     days,
+		Start:    start,
+		End:      end,
+		Explicit: true,
+	}
+}
+
+// SincePeriod возвращает период от момента from до момента to
+func SincePeriod(from, to time.Time, loc *time.Location) Period {
+	return Period{
+		Kind:  PeriodSince,
+		Start: from.In(loc),
+		End:   to.In(loc),
+	}
+}
+
+// LastHoursPeriod возвращает период за последние hours часов до текущего момента
+func LastHoursPeriod(hours int, loc *time.Location) Period {
+	if hours < 1 {
+		hours = 1
+	}
+	end := time.Now().In(loc).Truncate(time.Minute)
+	return Period{
+		Kind:  PeriodHours,
+		Hours: hours,
+		Start: end.Add(-time.Duration(hours) * time.Hour),
+		End:   end,
+	}
+}
+
+// HoursRangePeriod возвращает интервал от from до to с точностью до минут ("с 10 до 14")
+func HoursRangePeriod(from, to time.Time, loc *time.Location) Period {
+	return Period{
+		Kind:  PeriodHours,
+		Start: from.In(loc),
+		End:   to.In(loc),
+	}
+}
+
+// ThreadPeriod возвращает период ветки ответов от первого сообщения first до последнего last включительно
+func ThreadPeriod(first, last time.Time, loc *time.Location) Period {
+	return Period{
+		Kind:  PeriodThread,
+		Start: first.In(loc),
+		End:   last.Add(time.Second).In(loc),
+	}
+}
+
+// NameIn возвращает название периода на языке lang
+func (p Period) NameIn(lang Language) string {
+	if lang.OrDefault() == LangEN {
+		return p.englishName()
+	}
+	return p.Name()
+}
+
+// Name возвращает человекочитаемое название периода
+func (p Period) Name() string {
+	if p.Kind == PeriodSince {
+		return "время с " + p.Start.Format("02.01 15:04")
+	}
+
+	if p.Kind == PeriodThread {
+		return "тред от " + p.Start.Format("02.01 15:04")
+	}
+
+	if p.Kind == PeriodHours {
+		if p.Hours == 1 {
+			return "последний час"
+		}
+		if p.Hours > 0 {
+			return fmt.Sprintf("последние %d %s", p.Hours, utils.Pluralize(p.Hours, "час", "часа", "часов"))
+		}
+		name := p.Start.Format("15:04") + "–" + p.End.Format("15:04")
+		if !dayStart(p.Start).Equal(dayStart(time.Now().In(p.Location()))) {
+			name = p.Start.Format("02.01") + " " + name
+		}
+		return name
+	}
+
+	if p.Kind == PeriodDay {
+		switch p.Days {
+		case 0:
+			return "сегодня"
+		case 1:
+			return "вчера"
+		case 2:
+			return "позавчера"
+		default:
+			return p.Start.Format("02.01.2006")
+		}
+	}
+
+	if p.Explicit {
+		if p.Days == 1 {
+			return p.Start.Format("02.01.2006")
+		}
+		return fmt.Sprintf("период %s — %s",
+			p.Start.Format("02.01.2006"), p.End.AddDate(0, 0, -1).Format("02.01.2006"))
+	}
+
+	if p.Days == 1 {
+		return "сегодня"
+	}
+	return fmt.Sprintf("последние %d %s", p.Days, utils.Pluralize(p.Days, "день", "дня", "дней"))
+}
+
+// englishName то же, что Name, но по-английски
+func (p Period) englishName() string {
+	switch p.Kind {
+	case PeriodSince:
+		return "the time since " + p.Start.Format("02.01 15:04")
+	case PeriodThread:
+		return "the thread from " + p.Start.Format("02.01 15:04")
+	case PeriodHours:
+		if p.Hours == 1 {
+			return "the last hour"
+		}
+		if p.Hours > 0 {
+			return fmt.Sprintf("the last %d hours", p.Hours)
+		}
+		name := p.Start.Format("15:04") + "–" + p.End.Format("15:04")
+		if !dayStart(p.Start).Equal(dayStart(time.Now().In(p.Location()))) {
+			name = p.Start.Format("02.01") + " " + name
+		}
+		return name
+	case PeriodDay:
+		switch p.Days {
+		case 0:
+			return "today"
+		case 1:
+			return "yesterday"
+		case 2:
+			return "the day before yesterday"
+		default:
+			return p.Start.Format("02.01.2006")
+		}
+	}
+
+	if p.Explicit {
+		if p.Days == 1 {
+			return p.Start.Format("02.01.2006")
+		}
+		return fmt.Sprintf("%s — %s", p.Start.Format("02.01.2006"), p.End.AddDate(0, 0, -1).Format("02.01.2006"))
+	}
+
+	if p.Days == 1 {
+		return "today"
+	}
+	return fmt.Sprintf("the last %d days", p.Days)
+}
+
+// Cacheable можно ли сохранять и переиспользовать резюме за этот период.
+// "Последние N часов" каждый раз сдвигаются, а ветка ответов может дорасти - их кешировать бессмысленно
+func (p Period) Cacheable() bool {
+	if p.Kind == PeriodHours {
+		return p.Hours == 0
+	}
+	return p.Kind != PeriodSince && p.Kind != PeriodThread
+}
+
+// Short период короче суток - живое обсуждение, а не целый день
+func (p Period) Short() bool {
+	return p.Kind == PeriodHours && p.End.Sub(p.Start) < 24*time.Hour
+}
+
+// Location возвращает таймзону, в которой посчитан период
+func (p Period) Location() *time.Location {
+	return p.Start.Location()
+}
+
+// Bounds возвращает границы периода в UTC - в таком виде время хранится в БД
+func (p Period) Bounds() (time.Time, time.Time) {
+	return p.Start.UTC(), p.End.UTC()
+}
+
+// dayStart возвращает полночь того дня, в который попадает t, в таймзоне t
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"summarybot/internal/database"
+	"summarybot/internal/utils"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/gorm"
+)
+
+const (
+	// qaHistoryDays насколько далеко назад ищем, если в вопросе нет периода
+	qaHistoryDays = 180
+	// maxQACandidates сколько найденных сообщений отдаем модели
+	maxQACandidates = 40
+	// maxQASemantic сколько из них добираем поиском по смыслу, если он включен
+	maxQASemantic = 15
+	// maxQASources сколько сообщений-источников показываем под ответом
+	maxQASources = 5
+)
+
+// QAService отвечает на вопросы о прошлом переписки по сохраненным сообщениям
+type QAService struct {
+	db         *gorm.DB
+	ai         *openai.Client
+	model      string
+	search     *SearchService
+	embeddings *EmbeddingService
+}
+
+func NewQAService(db *gorm.DB, ai *openai.Client, model string, search *SearchService, embeddings *EmbeddingService) *QAService {
+	return &QAService{
+		db:         db,
+		ai:         ai,
+		model:      model,
+		search:     search,
+		embeddings: embeddings,
+	}
+}
+
+// QuestionRequest вопрос о прошлом переписки
+type QuestionRequest struct {
+	ChatID   int64
+	TopicID  int
+	Question string
+	// Period - где искать; пустой - последние qaHistoryDays дней
+	Period   Period
+	Language Language
+	Location *time.Location
+}
+
+// QuestionSource сообщение, на которое опирается ответ
+type QuestionSource struct {
+	Author string
+	Time   time.Time
+	Link   string
+}
+
+// QuestionAnswer ответ модели и сообщения, на которые он ссылается.
+// Found - сколько подходящих сообщений нашлось; 0 - модель не спрашивали
+type QuestionAnswer struct {
+	Text    string
+	Sources []QuestionSource
+	Found   int
+}
+
+// qaResponse ответ модели в JSON
+type qaResponse struct {
+	Answer  string `json:"answer"`
+	Sources []int  `json:"sources"`
+}
+
+// Answer ищет в истории чата сообщения по словам из вопроса и просит модель ответить по ним
+// с указанием, кто и когда это писал
+func (s *QAService) Answer(req QuestionRequest) (QuestionAnswer, error) {
+	candidates, err := s.candidates(req)
+	if err != nil || len(candidates) == 0 {
+		return QuestionAnswer{}, err
+	}
+
+	loc := req.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var transcript strings.Builder
+	for i, msg := range candidates {
+		transcript.WriteString(fmt.Sprintf("[%d] %s %s: %s\n",
+			i+1, msg.Timestamp.In(loc).Format("02.01.2006 15:04"), authorName(msg), messageText(msg, req.Language)))
+	}
+
+	userPrompt := fmt.Sprintf("Вопрос: %s\n\nНайденные сообщения:\n%s", req.Question, transcript.String())
+	raw, err := s.chatJSON(qaSystemPrompt+req.Language.OrDefault().info().answerInstruction, userPrompt)
+	if err != nil {
+		return QuestionAnswer{}, err
+	}
+
+	var resp qaResponse
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		return QuestionAnswer{}, fmt.Errorf("модель вернула не JSON: %w", err)
+	}
+
+	answer := QuestionAnswer{Text: strings.TrimSpace(resp.Answer), Found: len(candidates)}
+	links := newChatLinkInfo(req.ChatID, candidates)
+	seen := make(map[int]bool)
+	for _, n := range resp.Sources {
+		if n < 1 || n > len(candidates) || seen[n] || len(answer.Sources) >= maxQASources {
+			continue
+		}
+		seen[n] = true
+		msg := candidates[n-1]
+		answer.Sources = append(answer.Sources, QuestionSource{
+			Author: authorName(msg),
+			Time:   msg.Timestamp.In(loc),
+			Link:   links.link(msg.TelegramMessageID),
+		})
+	}
+
+	return answer, nil
+}
+
+// candidates отбирает сообщения, в которых больше всего слов из вопроса, и ставит их по порядку времени.
+// Если включен поиск по смыслу, добавляет похожие по смыслу сообщения - они находятся и без общих слов
+func (s *QAService) candidates(req QuestionRequest) ([]database.Message, error) {
+	start, end := req.Period.Bounds()
+	if req.Period.Start.IsZero() {
+		end = time.Now().UTC()
+		start = end.AddDate(0, 0, -qaHistoryDays)
+	}
+
+	query := SearchQuery{
+		ChatID:   req.ChatID,
+		TopicID:  req.TopicID,
+		Terms:    utils.QuestionKeywords(req.Question),
+		MatchAny: true,
+		Start:    start,
+		End:      end,
+		Limit:    maxQACandidates,
+	}
+
+	var semantic SearchPage
+	if s.embeddings.Enabled() {
+		semanticQuery := query
+		semanticQuery.Limit = maxQASemantic
+		var err error
+		if semantic, err = s.embeddings.Search(semanticQuery, req.Question); err != nil {
+			// без поиска по смыслу ответим по словам
+			log.Printf("Ошибка поиска по смыслу в чате %d: %v", req.ChatID, err)
+		}
+		query.Limit = maxQACandidates - len(semantic.Results)
+	}
+
+	page, err := s.search.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]database.Message, 0, len(page.Results)+len(semantic.Results))
+	seen := make(map[uint]bool)
+	for _, result := range append(semantic.Results, page.Results...) {
+		if !seen[result.ID] {
+			seen[result.ID] = true
+			messages = append(messages, result.Message)
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Timestamp.Before(messages[j].Timestamp)
+	})
+	return messages, nil
+}
+
+func (s *QAService) chatJSON(systemPrompt, userPrompt string) (string, error) {
+	resp, err := s.ai.CreateChatCompletion(
+		context.Background(),
+		openai.ChatCompletionRequest{
+			Model: s.model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+				{Role: openai.ChatMessageRoleUser, Content: userPrompt},
+			},
+			MaxTokens:   800,
+			Temperature: 0.2,
+			ResponseFormat: &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+			},
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("пустой ответ от AI")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// qaSystemPrompt системный промпт для ответов на вопросы по истории чата
+const qaSystemPrompt = `Ты бот группового чата и отвечаешь на вопрос о том, что было в переписке раньше.
+
+Тебе дают вопрос и пронумерованные сообщения из истории чата, найденные по словам из вопроса.
+Формат сообщения: [номер] дата время Автор: текст
+
+ПРАВИЛА:
+- Отвечай ТОЛЬКО по этим сообщениям, ничего не выдумывай
+- Для каждого факта называй, кто это писал и когда (дату в формате ДД.ММ.ГГГГ)
+- Часть сообщений могла попасть случайно - не обращай на них внимания
+- Если ответа в сообщениях нет, так и скажи
+- Коротко: 1-4 предложения, можно по-пацански, но по делу
+
+Ответь JSON-объектом: {"answer": "текст ответа", "sources": [номера сообщений, на которые опирается ответ]}`
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"summarybot/internal/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// searchScanLimit сколько последних сообщений просматриваем, когда FTS5 недоступен
+	searchScanLimit = 20000
+	// snippetTokens сколько слов вокруг совпадения показываем в результатах поиска
+	snippetTokens = 16
+	// maxSnippetRunes длина отрывка, когда FTS5 недоступен
+	maxSnippetRunes = 200
+)
+
+// Маркеры совпадения в отрывке: подставляются вместо тегов, чтобы не сломать экранирование HTML
+const (
+	SnippetMatchStart = "\x02"
+	SnippetMatchEnd   = "\x03"
+)
+
+// ftsSchema полнотекстовый индекс по messages и триггеры, которые держат его в актуальном состоянии
+var ftsSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+		text, content='messages', content_rowid='id', tokenize='unicode61 remove_diacritics 2')`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_ai AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts(rowid, text) VALUES (new.id, new.text);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_ad AFTER DELETE ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_au AFTER UPDATE OF text ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+		INSERT INTO messages_fts(rowid, text) VALUES (new.id, new.text);
+	END`,
+}
+
+// SearchService полнотекстовый поиск по сообщениям чата.
+// Если SQLite собран без FTS5, ищет перебором последних сообщений
+type SearchService struct {
+	db  *gorm.DB
+	fts bool
+}
+
+func NewSearchService(db *gorm.DB) *SearchService {
+	s := &SearchService{db: db}
+	if err := s.initFTS(); err != nil {
+		log.Printf("FTS5 недоступен, поиск будет перебором: %v", err)
+	} else {
+		s.fts = true
+	}
+	return s
+}
+
+// SearchQuery параметры поиска
+type SearchQuery struct {
+	ChatID  int64
+	TopicID int
+	// Terms - основы слов (utils.SearchTerms), ищутся по префиксу
+	Terms []string
+	// MatchAny - достаточно одного слова; иначе нужны все
+	MatchAny bool
+	// Start, End - ограничение по времени, пустые - без ограничения
+	Start  time.Time
+	End    time.Time
+	Offset int
+	Limit  int
+}
+
+// SearchResult найденное сообщение и отрывок с совпадением, размеченный SnippetMatchStart/End
+type SearchResult struct {
+	database.Message
+	Snippet string
+}
+
+// SearchPage страница результатов и сколько всего нашлось
+type SearchPage struct {
+	Results []SearchResult
+	Total   int
+}
+
+// Search ищет сообщения по словам, самые подходящие - первыми
+func (s *SearchService) Search(q SearchQuery) (SearchPage, error) {
+	if len(q.Terms) == 0 {
+		return SearchPage{}, nil
+	}
+	if s.fts {
+		return s.searchFTS(q)
+	}
+	return s.searchScan(q)
+}
+
+// initFTS создает индекс и триггеры; при первом создании индекс заполняется уже сохраненными сообщениями
+func (s *SearchService) initFTS() error {
+	var exists int64
+	s.db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'messages_fts'").Scan(&exists)
+
+	for _, stmt := range ftsSchema {
+		if err := s.db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	if exists == 0 {
+		log.Printf("Строим полнотекстовый индекс сообщений...")
+		return s.db.Exec("INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')").Error
+	}
+	return nil
+}
+
+func (s *SearchService) searchFTS(q SearchQuery) (SearchPage, error) {
+	match := ftsMatch(q.Terms, q.MatchAny)
+
+	where := "messages_fts MATCH ? AND m.chat_id = ?"
+	args := []interface{}{match, q.ChatID}
+	if q.TopicID != 0 {
+		where += " AND m.topic_id = ?"
+		args = append(args, q.TopicID)
+	}
+	if !q.Start.IsZero() {
+		where += " AND m.timestamp >= ? AND m.timestamp < ?"
+		args = append(args, q.Start.UTC(), q.End.UTC())
+	}
+
+	var page SearchPage
+	var total int64
+	err := s.db.Raw("SELECT COUNT(*) FROM messages_fts JOIN messages m ON m.id = messages_fts.rowid WHERE "+where, args...).
+		Scan(&total).Error
+	if err != nil {
+		return page, err
+	}
+	page.Total = int(total)
+
+	snippet := fmt.Sprintf("snippet(messages_fts, 0, '%s', '%s', '…', %d)", SnippetMatchStart, SnippetMatchEnd, snippetTokens)
+	err = s.db.Raw("SELECT m.*, "+snippet+" AS snippet FROM messages_fts JOIN messages m ON m.id = messages_fts.rowid WHERE "+where+
+		" ORDER BY bm25(messages_fts), m.timestamp DESC LIMIT ? OFFSET ?", append(args, q.Limit, q.Offset)...).
+		Scan(&page.Results).Error
+	return page, err
+}
+
+// ftsMatch собирает выражение MATCH: каждое слово в кавычках и по префиксу
+func ftsMatch(terms []string, any bool) string {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+	if any {
+		return strings.Join(quoted, " OR ")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// searchScan поиск перебором: SQLite без FTS5 не умеет сравнивать кириллицу без учета регистра,
+// поэтому последние сообщения проверяются в Go. Чем больше слов совпало, тем выше результат
+func (s *SearchService) searchScan(q SearchQuery) (SearchPage, error) {
+	query := s.db.Where("chat_id = ?", q.ChatID)
+	if q.TopicID != 0 {
+		query = query.Where("topic_id = ?", q.TopicID)
+	}
+	if !q.Start.IsZero() {
+		query = query.Where("timestamp >= ? AND timestamp < ?", q.Start.UTC(), q.End.UTC())
+	}
+
+	var messages []database.Message
+	if err := query.Order("timestamp DESC").Limit(searchScanLimit).Find(&messages).Error; err != nil {
+		return SearchPage{}, err
+	}
+
+	type scored struct {
+		msg   database.Message
+		score int
+	}
+	var found []scored
+	for _, msg := range messages {
+		text := strings.ToLower(msg.Text)
+		score := 0
+		for _, term := range q.Terms {
+			if strings.Contains(text, term) {
+				score++
+			}
+		}
+		if score == len(q.Terms) || (q.MatchAny && score > 0) {
+			found = append(found, scored{msg, score})
+		}
+	}
+
+	// Сообщения уже от новых к старым - при равном счете выигрывают свежие
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].score > found[j].score
+	})
+
+	page := SearchPage{Total: len(found)}
+	for i := q.Offset; i < len(found) && len(page.Results) < q.Limit; i++ {
+		page.Results = append(page.Results, SearchResult{Message: found[i].msg, Snippet: scanSnippet(found[i].msg.Text)})
+	}
+	return page, nil
+}
+
+// scanSnippet начало сообщения вместо отрывка FTS5
+func scanSnippet(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxSnippetRunes {
+		return text
+	}
+	return string(runes[:maxSnippetRunes]) + "…"
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"summarybot/internal/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SettingsService struct {
+	db                   *gorm.DB
+	defaultLoc           *time.Location
+	defaultDigestEnabled bool
+	defaultDigestTime    string
+}
+
+func NewSettingsService(db *gorm.DB, defaultTimezone string, digestEnabled bool, digestTime string) *SettingsService {
+	loc, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		log.Printf("Неизвестная таймзона по умолчанию %q, используем UTC: %v", defaultTimezone, err)
+		loc = time.UTC
+	}
+
+	if _, _, err := ParseClock(digestTime); err != nil {
+		log.Printf("Некорректное время дайджеста по умолчанию %q, используем 09:00: %v", digestTime, err)
+		digestTime = "09:00"
+	}
+
+	return &SettingsService{
+		db:                   db,
+		defaultLoc:           loc,
+		defaultDigestEnabled: digestEnabled,
+		defaultDigestTime:    digestTime,
+	}
+}
+
+// Get возвращает настройки чата; если их нет - пустые настройки с ChatID
+func (s *SettingsService) Get(chatID int64) database.ChatSettings {
+	var settings database.ChatSettings
+	if err := s.db.Where("chat_id = ?", chatID).First(&settings).Error; err != nil {
+		return database.ChatSettings{ChatID: chatID}
+	}
+	return settings
+}
+
+// Location возвращает таймзону чата или таймзону по умолчанию
+func (s *SettingsService) Location(chatID int64) *time.Location {
+	settings := s.Get(chatID)
+	if settings.Timezone == "" {
+		return s.defaultLoc
+	}
+
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		log.Printf("Некорректная таймзона %q у чата %d: %v", settings.Timezone, chatID, err)
+		return s.defaultLoc
+	}
+	return loc
+}
+
+// DefaultLocation возвращает таймзону по умолчанию из конфига
+func (s *SettingsService) DefaultLocation() *time.Location {
+	return s.defaultLoc
+}
+
+// SetTimezone сохраняет таймзону чата
+func (s *SettingsService) SetTimezone(chatID int64, name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || name == "Local" {
+		return nil, fmt.Errorf("неизвестная таймзона %q", name)
+	}
+
+	if err := s.update(chatID, map[string]interface{}{"timezone": loc.String()}); err != nil {
+		return nil, err
+	}
+	return loc, nil
+}
+
+// DigestEnabled включен ли ежедневный дайджест в чате
+func (s *SettingsService) DigestEnabled(settings database.ChatSettings) bool {
+	if settings.DigestEnabled == nil {
+		return s.defaultDigestEnabled
+	}
+	return *settings.DigestEnabled
+}
+
+// DigestTime возвращает время дайджеста чата (по местному времени чата)
+func (s *SettingsService) DigestTime(settings database.ChatSettings) (int, int) {
+	if settings.DigestTime != "" {
+		if hour, minute, err := ParseClock(settings.DigestTime); err == nil {
+			return hour, minute
+		}
+	}
+	hour, minute, _ := ParseClock(s.defaultDigestTime)
+	return hour, minute
+}
+
+// SetDigestEnabled включает или выключает ежедневный дайджест в чате
+func (s *SettingsService) SetDigestEnabled(chatID int64, enabled bool) error {
+	return s.update(chatID, map[string]interface{}{"digest_enabled": enabled})
+}
+
+// SetDigestTime сохраняет время дайджеста в формате ЧЧ:ММ
+func (s *SettingsService) SetDigestTime(chatID int64, value string) error {
+	hour, minute, err := ParseClock(value)
+	if err != nil {
+		return err
+	}
+	return s.update(chatID, map[string]interface{}{
+		"digest_time": fmt.Sprintf("%02d:%02d", hour, minute),
+	})
+}
+
+// MarkDigestSent запоминает, когда в чат ушел последний дайджест
+func (s *SettingsService) MarkDigestSent(chatID int64, at time.Time) error {
+	return s.update(chatID, map[string]interface{}{"digest_sent_at": at.UTC()})
+}
+
+// SummaryStyle возвращает стиль резюме чата
+func (s *SettingsService) SummaryStyle(chatID int64) SummaryStyle {
+	return SummaryStyle(s.Get(chatID).SummaryStyle).OrDefault()
+}
+
+// SetSummaryStyle сохраняет стиль резюме чата
+func (s *SettingsService) SetSummaryStyle(chatID int64, style SummaryStyle) error {
+	return s.update(chatID, map[string]interface{}{"summary_style": string(style.OrDefault())})
+}
+
+// Language возвращает язык резюме чата
+func (s *SettingsService) Language(chatID int64) Language {
+	return Language(s.Get(chatID).Language).OrDefault()
+}
+
+// SetLanguage сохраняет язык резюме чата
+func (s *SettingsService) SetLanguage(chatID int64, lang Language) error {
+	return s.update(chatID, map[string]interface{}{"language": string(lang.OrDefault())})
+}
+
+// ParseClock разбирает время в формате ЧЧ:ММ
+func ParseClock(value string) (int, int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, 0, fmt.Errorf("некорректное время %q, нужно ЧЧ:ММ", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// update обновляет поля настроек чата, создавая запись при необходимости
+func (s *SettingsService) update(chatID int64, fields map[string]interface{}) error {
+	var settings database.ChatSettings
+	err := s.db.Where("chat_id = ?", chatID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		settings = database.ChatSettings{ChatID: chatID, CreatedAt: time.Now()}
+		if err := s.db.Create(&settings).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return s.db.Model(&settings).Updates(fields).Error
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"summarybot/internal/database"
+	"summarybot/internal/utils"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxDigestLinks сколько последних ссылок попадает в подборку
+	maxDigestLinks = 60
+	// linkContextMessages сколько сообщений до и после ссылки показываем модели для описания
+	linkContextMessages = 3
+	// maxLinkContextRunes сколько символов одного сообщения контекста отдаем модели
+	maxLinkContextRunes = 300
+)
+
+// LinkService хранит ссылки из чата и собирает из них подборки
+type LinkService struct {
+	db    *gorm.DB
+	ai    *openai.Client
+	model string
+}
+
+func NewLinkService(db *gorm.DB, ai *openai.Client, model string) *LinkService {
+	return &LinkService{
+		db:    db,
+		ai:    ai,
+		model: model,
+	}
+}
+
+// SharedLinkInfo ссылка в подборке: первое появление и сколько раз ее кидали
+type SharedLinkInfo struct {
+	database.SharedLink
+	Shares int
+}
+
+// LinkGroup ссылки одного домена
+type LinkGroup struct {
+	Domain string
+	Links  []SharedLinkInfo
+}
+
+// SaveLinks сохраняет ссылки из сообщения msg
+func (s *LinkService) SaveLinks(msg database.Message, urls []string) {
+	for _, link := range urls {
+		shared := database.SharedLink{
+			ChatID:            msg.ChatID,
+			TopicID:           msg.TopicID,
+			TelegramMessageID: msg.TelegramMessageID,
+			UserID:            msg.UserID,
+			Username:          msg.Username,
+			FirstName:         msg.FirstName,
+			URL:               link,
+			Domain:            utils.LinkDomain(link),
+			Timestamp:         msg.Timestamp,
+			CreatedAt:         time.Now(),
+		}
+		if err := s.db.Create(&shared).Error; err != nil {
+			log.Printf("Ошибка сохранения ссылки: %v", err)
+		}
+	}
+}
+
+// ReplaceLinks обновляет ссылки отредактированного сообщения, если их набор поменялся.
+// Описания неизменившегося набора ссылок сохраняются
+func (s *LinkService) ReplaceLinks(msg database.Message, urls []string) {
+	var old []database.SharedLink
+	s.db.Where("chat_id = ? AND telegram_message_id = ?", msg.ChatID, msg.TelegramMessageID).Find(&old)
+
+	same := len(old) == len(urls)
+	for i := 0; same && i < len(old); i++ {
+		same = old[i].URL == urls[i]
+	}
+	if same {
+		return
+	}
+
+	if err := s.db.Where("chat_id = ? AND telegram_message_id = ?", msg.ChatID, msg.TelegramMessageID).
+		Delete(&database.SharedLink{}).Error; err != nil {
+		log.Printf("Ошибка удаления ссылок сообщения: %v", err)
+		return
+	}
+	s.SaveLinks(msg, urls)
+}
+
+// LinkDigest собирает ссылки чата (или темы форума) за период, сгруппированные по доменам:
+// сначала домены, которые кидали чаще, внутри домена - по времени.
+// Ссылкам без описания на языке lang описание дописывает модель по соседним сообщениям
+func (s *LinkService) LinkDigest(chatID int64, topicID int, period Period, lang Language) ([]LinkGroup, error) {
+	start, end := period.Bounds()
+	query := s.db.Where("chat_id = ? AND timestamp >= ? AND timestamp < ?", chatID, start, end)
+	if topicID != 0 {
+		query = query.Where("topic_id = ?", topicID)
+	}
+
+	var shared []database.SharedLink
+	if err := query.Order("timestamp DESC").Find(&shared).Error; err != nil {
+		return nil, err
+	}
+
+	// Одну и ту же ссылку кидают по несколько раз - оставляем первое появление
+	byURL := make(map[string]*SharedLinkInfo)
+	var links []*SharedLinkInfo
+	for i := len(shared) - 1; i >= 0; i-- {
+		if info, ok := byURL[shared[i].URL]; ok {
+			info.Shares++
+			continue
+		}
+		info := &SharedLinkInfo{SharedLink: shared[i], Shares: 1}
+		byURL[info.URL] = info
+		links = append(links, info)
+	}
+	if len(links) > maxDigestLinks {
+		links = links[len(links)-maxDigestLinks:]
+	}
+
+	if err := s.describeLinks(links, lang); err != nil {
+		// Подборка полезна и без описаний
+		log.Printf("Ошибка описания ссылок чата %d: %v", chatID, err)
+	}
+
+	groups := make(map[string]*LinkGroup)
+	var result []*LinkGroup
+	for _, info := range links {
+		group, ok := groups[info.Domain]
+		if !ok {
+			group = &LinkGroup{Domain: info.Domain}
+			groups[info.Domain] = group
+			result = append(result, group)
+		}
+		group.Links = append(group.Links, *info)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return len(result[i].Links) > len(result[j].Links)
+	})
+
+	digest := make([]LinkGroup, 0, len(result))
+	for _, group := range result {
+		digest = append(digest, *group)
+	}
+	return digest, nil
+}
+
+// describeLinks одним запросом просит модель описать ссылки без описания и сохраняет описания
+func (s *LinkService) describeLinks(links []*SharedLinkInfo, lang Language) error {
+	lang = lang.OrDefault()
+
+	var pending []*SharedLinkInfo
+	for _, info := range links {
+		if info.Description == "" || info.DescriptionLang != string(lang) {
+			pending = append(pending, info)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var prompt strings.Builder
+	for i, info := range pending {
+		prompt.WriteString(fmt.Sprintf("### %d\nСсылка: %s\nКонтекст:\n%s\n", i+1, info.URL, s.linkContext(info.SharedLink)))
+	}
+
+	raw, err := s.chatJSON(fmt.Sprintf(linkDescriptionPrompt, lang.info().instruction), prompt.String())
+	if err != nil {
+		return err
+	}
+
+	var descriptions map[string]string
+	if err := json.Unmarshal([]byte(raw), &descriptions); err != nil {
+		return fmt.Errorf("модель вернула не JSON: %w", err)
+	}
+
+	for i, info := range pending {
+		description := strings.TrimSpace(descriptions[strconv.Itoa(i+1)])
+		if description == "" {
+			continue
+		}
+		info.Description, info.DescriptionLang = description, string(lang)
+		s.db.Model(&database.SharedLink{}).
+			Where("chat_id = ? AND url = ?", info.ChatID, info.URL).
+			Updates(map[string]interface{}{"description": description, "description_lang": string(lang)})
+	}
+	return nil
+}
+
+// linkContext сообщение со ссылкой и несколько соседних сообщений вокруг него
+func (s *LinkService) linkContext(link database.SharedLink) string {
+	var messages []database.Message
+	s.db.Where("chat_id = ? AND telegram_message_id BETWEEN ? AND ?",
+		link.ChatID, link.TelegramMessageID-linkContextMessages, link.TelegramMessageID+linkContextMessages).
+		Order("telegram_message_id ASC").
+		Find(&messages)
+
+	var lines strings.Builder
+	for _, msg := range messages {
+		text := []rune(messageText(msg, DefaultLanguage))
+		if len(text) > maxLinkContextRunes {
+			text = append(text[:maxLinkContextRunes], '…')
+		}
+		marker := ""
+		if msg.TelegramMessageID == link.TelegramMessageID {
+			marker = " (ссылка отсюда)"
+		}
+		lines.WriteString(fmt.Sprintf("%s%s: %s\n", authorName(msg), marker, string(text)))
+	}
+	return lines.String()
+}
+
+func (s *LinkService) chatJSON(systemPrompt, userPrompt string) (string, error) {
+	resp, err := s.ai.CreateChatCompletion(
+		context.Background(),
+		openai.ChatCompletionRequest{
+			Model: s.model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+				{Role: openai.ChatMessageRoleUser, Content: userPrompt},
+			},
+			MaxTokens:   1500,
+			Temperature: 0.3,
+			ResponseFormat: &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+			},
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("пустой ответ от AI")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// linkDescriptionPrompt системный промпт для описаний ссылок; %s - инструкция про язык ответа
+const linkDescriptionPrompt = `Ты помогаешь собрать подборку ссылок из группового чата.
+
+Для каждой пронумерованной ссылки тебе дают саму ссылку и сообщения вокруг нее.
+Напиши одно короткое предложение (до 15 слов): что это за ссылка и зачем ее скинули в чат.
+Опирайся на контекст и на саму ссылку, не выдумывай содержимое страницы, которого не видно.
+
+Ответь JSON-объектом, где ключ - номер ссылки, значение - описание: {"1": "...", "2": "..."}%s`
@@ -3,6 +3,8 @@ package services
 import (
 	"fmt"
 	"math/rand"
+	"sort"
+	"summarybot/internal/database"
 	"time"
 
 	"gopkg.in/telebot.v3"
@@ -31,7 +33,7 @@ func (s *StatsService) GetRandomActiveUser(chatID int64) (*telebot.User, error)
 		Count     int64
 	}
 
-	fourteenDaysAgo := time.Now().AddDate(0, 0, -14)
+	fourteenDaysAgo := time.Now().AddDate(0, 0, -14).UTC()
 
 	query := `
 		SELECT user_id, username, first_name, COUNT(*) as count 
@@ -51,7 +53,7 @@ func (s *StatsService) GetRandomActiveUser(chatID int64) (*telebot.User, error)
 
 	if len(users) == 0 {
 		// Fallback на 30 дней
-		thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
+		thirtyDaysAgo := time.Now().AddDate(0, 0, -30).UTC()
 		err = s.db.Raw(query, chatID, thirtyDaysAgo).Scan(&users).Error
 		if err != nil || len(users) == 0 {
 			return nil, fmt.Errorf("нет активных пользователей")
@@ -88,3 +90,104 @@ func (s *StatsService) GetTopSwearers(chatID int64, limit int) []SwearStat {
 
 	return stats
 }
+
+// AuthorStat сколько сообщений написал участник
+type AuthorStat struct {
+	Username  string
+	FirstName string
+	Count     int64
+}
+
+// PeriodStats статистика сообщений чата (или одного участника) за период
+type PeriodStats struct {
+	Messages     int64
+	Participants int64
+	TopAuthors   []AuthorStat
+	// BusiestHour самый активный час по времени start, -1 если сообщений нет
+	BusiestHour int
+}
+
+// GetPeriodStats считает статистику сообщений с start до end; userID != 0 - только по одному участнику
+func (s *StatsService) GetPeriodStats(chatID, userID int64, start, end time.Time, topLimit int) PeriodStats {
+	stats := PeriodStats{BusiestHour: -1}
+
+	query := func() *gorm.DB {
+		q := s.db.Table("messages").
+			Where("chat_id = ? AND timestamp >= ? AND timestamp < ?", chatID, start.UTC(), end.UTC())
+		if userID != 0 {
+			q = q.Where("user_id = ?", userID)
+		}
+		return q
+	}
+
+	query().Count(&stats.Messages)
+	if stats.Messages == 0 {
+		return stats
+	}
+
+	query().Distinct("user_id").Count(&stats.Participants)
+
+	query().Select("username, first_name, COUNT(*) as count").
+		Group("user_id, username, first_name").
+		Order("count DESC").
+		Limit(topLimit).
+		Scan(&stats.TopAuthors)
+
+	var timestamps []time.Time
+	query().Pluck("timestamp", &timestamps)
+
+	var byHour [24]int
+	for _, ts := range timestamps {
+		byHour[ts.In(start.Location()).Hour()]++
+	}
+	for hour, count := range byHour {
+		if stats.BusiestHour < 0 || count > byHour[stats.BusiestHour] {
+			stats.BusiestHour = hour
+		}
+	}
+
+	return stats
+}
+
+// MessagesStats считает ту же статистику по уже выбранным сообщениям (например, по ветке ответов).
+// Самый активный час считается по времени loc
+func MessagesStats(messages []database.Message, loc *time.Location, topLimit int) PeriodStats {
+	stats := PeriodStats{Messages: int64(len(messages)), BusiestHour: -1}
+	if len(messages) == 0 {
+		return stats
+	}
+
+	byAuthor := make(map[int64]*AuthorStat)
+	var byHour [24]int
+	for _, msg := range messages {
+		author, ok := byAuthor[msg.UserID]
+		if !ok {
+			author = &AuthorStat{Username: msg.Username, FirstName: msg.FirstName}
+			byAuthor[msg.UserID] = author
+		}
+		author.Count++
+		byHour[msg.Timestamp.In(loc).Hour()]++
+	}
+
+	stats.Participants = int64(len(byAuthor))
+	for _, author := range byAuthor {
+		stats.TopAuthors = append(stats.TopAuthors, *author)
+	}
+	sort.SliceStable(stats.TopAuthors, func(i, j int) bool {
+		if stats.TopAuthors[i].Count != stats.TopAuthors[j].Count {
+			return stats.TopAuthors[i].Count > stats.TopAuthors[j].Count
+		}
+		return stats.TopAuthors[i].FirstName < stats.TopAuthors[j].FirstName
+	})
+	if len(stats.TopAuthors) > topLimit {
+		stats.TopAuthors = stats.TopAuthors[:topLimit]
+	}
+
+	for hour, count := range byHour {
+		if stats.BusiestHour < 0 || count > byHour[stats.BusiestHour] {
+			stats.BusiestHour = hour
+		}
+	}
+
+	return stats
+}
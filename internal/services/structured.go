@@ -0,0 +1,246 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	// maxSummaryTopics больше тем в резюме не показываем
+	maxSummaryTopics = 8
+	// maxSummaryItems лимит для ссылок, решений и позиций
+	maxSummaryItems = 10
+)
+
+// summaryJSONFormat дописывается к системному промпту: модель отвечает JSON, а верстку делает Go
+const summaryJSONFormat = `
+
+ФОРМАТ ОТВЕТА - СТРОГО ОДИН JSON-объект, без markdown, HTML и пояснений:
+{
+  "topics": [
+    {"title": "короткое название темы", "emoji": "один эмодзи", "description": "1-2 предложения о теме", "message_ids": [1234, 1240]}
+  ],
+  "links": [
+    {"url": "https://...", "description": "что по ссылке"}
+  ],
+  "decisions": ["о чем договорились"],
+  "positions": ["за что топил или против чего выступал"]
+}
+
+- Все поля обязательны, если нечего писать - пустой массив []
+- "message_ids" - номера сообщений из переписки, если их нет - []
+- Никаких HTML-тегов внутри строк`
+
+// SummaryTopic одна тема резюме
+type SummaryTopic struct {
+	Title       string `json:"title"`
+	Emoji       string `json:"emoji"`
+	Description string `json:"description"`
+	MessageIDs  []int  `json:"message_ids"`
+}
+
+// SummaryLink полезная ссылка из переписки
+type SummaryLink struct {
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+// StructuredSummary резюме в структурированном виде - из него рендерятся все форматы
+type StructuredSummary struct {
+	Topics    []SummaryTopic `json:"topics"`
+	Links     []SummaryLink  `json:"links"`
+	Decisions []string       `json:"decisions"`
+	Positions []string       `json:"positions"`
+}
+
+// parseStructuredSummary достает JSON из ответа модели и проверяет его
+func parseStructuredSummary(raw string, links chatLinkInfo) (*StructuredSummary, error) {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimPrefix(raw, "```json")
+	raw = strings.TrimPrefix(raw, "```")
+	raw = strings.TrimSuffix(raw, "```")
+
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("в ответе модели нет JSON")
+	}
+
+	var summary StructuredSummary
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &summary); err != nil {
+		return nil, fmt.Errorf("некорректный JSON резюме: %w", err)
+	}
+
+	if err := summary.validate(links); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// parseOrRepairSummary разбирает ответ модели, а если он битый - один раз просит модель его починить
+func (s *SummaryService) parseOrRepairSummary(raw string, links chatLinkInfo) (*StructuredSummary, error) {
+	summary, err := parseStructuredSummary(raw, links)
+	if err == nil {
+		return summary, nil
+	}
+
+	log.Printf("Модель вернула битое резюме (%v), пробуем починить", err)
+
+	repaired, repairErr := s.completeJSON(
+		"Ты исправляешь JSON. Верни ТОЛЬКО исправленный JSON-объект без пояснений."+summaryJSONFormat,
+		fmt.Sprintf("Ошибка: %v\n\nИсходный ответ:\n%s", err, raw),
+		s.maxTokens,
+	)
+	if repairErr != nil {
+		return nil, repairErr
+	}
+
+	return parseStructuredSummary(repaired, links)
+}
+
+// validate чистит резюме: пустые темы, чужие номера сообщений, кривые ссылки
+func (r *StructuredSummary) validate(links chatLinkInfo) error {
+	topics := make([]SummaryTopic, 0, len(r.Topics))
+	for _, topic := range r.Topics {
+		topic.Title = strings.TrimSpace(topic.Title)
+		topic.Emoji = strings.TrimSpace(topic.Emoji)
+		topic.Description = strings.TrimSpace(topic.Description)
+		if topic.Title == "" && topic.Description == "" {
+			continue
+		}
+
+		ids := make([]int, 0, len(topic.MessageIDs))
+		for _, id := range topic.MessageIDs {
+			if links.known[id] {
+				ids = append(ids, id)
+			}
+		}
+		topic.MessageIDs = ids
+
+		topics = append(topics, topic)
+		if len(topics) == maxSummaryTopics {
+			break
+		}
+	}
+
+	if len(topics) == 0 {
+		return fmt.Errorf("в резюме нет ни одной темы")
+	}
+	r.Topics = topics
+
+	validLinks := make([]SummaryLink, 0, len(r.Links))
+	for _, link := range r.Links {
+		link.URL = strings.TrimSpace(link.URL)
+		link.Description = strings.TrimSpace(link.Description)
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		validLinks = append(validLinks, link)
+		if len(validLinks) == maxSummaryItems {
+			break
+		}
+	}
+	r.Links = validLinks
+
+	r.Decisions = cleanItems(r.Decisions)
+	r.Positions = cleanItems(r.Positions)
+
+	return nil
+}
+
+// cleanItems убирает пустые строки и обрезает список до лимита
+func cleanItems(items []string) []string {
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+		if len(result) == maxSummaryItems {
+			break
+		}
+	}
+	return result
+}
+
+// JSON сериализует резюме для хранения в БД
+func (r *StructuredSummary) JSON() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// summaryView данные для шаблонов резюме
+type summaryView struct {
+	*StructuredSummary
+	Participant bool
+	L           summaryLabels
+	links       chatLinkInfo
+}
+
+// TopicLink ссылка на первое сообщение темы
+func (v summaryView) TopicLink(topic SummaryTopic) string {
+	if len(topic.MessageIDs) == 0 {
+		return ""
+	}
+	return v.links.link(topic.MessageIDs[0])
+}
+
+var summaryHTMLTemplate = htmltemplate.Must(htmltemplate.New("summary").Parse(
+	`{{if .Participant}}🗣 <b>{{.L.Participant}}:</b>{{else}}🔥 <b>{{.L.Topics}}:</b>{{end}}
+{{range .Topics}}• {{with .Emoji}}{{.}} {{end}}<b>{{.Title}}</b>{{with .Description}} - {{.}}{{end}}{{with $.TopicLink .}} <a href="{{.}}">↗</a>{{end}}
+{{end}}{{if .Positions}}
+🎯 <b>{{.L.Positions}}:</b>
+{{range .Positions}}• {{.}}
+{{end}}{{end}}{{if .Decisions}}
+✅ <b>{{.L.Decisions}}:</b>
+{{range .Decisions}}• {{.}}
+{{end}}{{end}}{{if .Links}}
+📍 <b>{{.L.Links}}:</b>
+{{range .Links}}• <a href="{{.URL}}">{{if .Description}}{{.Description}}{{else}}{{.URL}}{{end}}</a>
+{{end}}{{end}}`))
+
+var summaryMarkdownTemplate = texttemplate.Must(texttemplate.New("summary").Parse(
+	`{{if .Participant}}## {{.L.Participant}}{{else}}## {{.L.Topics}}{{end}}
+
+{{range .Topics}}- {{with .Emoji}}{{.}} {{end}}**{{.Title}}**{{with .Description}} — {{.}}{{end}}{{with $.TopicLink .}} ([↗]({{.}})){{end}}
+{{end}}{{if .Positions}}
+## {{.L.Positions}}
+
+{{range .Positions}}- {{.}}
+{{end}}{{end}}{{if .Decisions}}
+## {{.L.Decisions}}
+
+{{range .Decisions}}- {{.}}
+{{end}}{{end}}{{if .Links}}
+## {{.L.Links}}
+
+{{range .Links}}- [{{if .Description}}{{.Description}}{{else}}{{.URL}}{{end}}]({{.URL}})
+{{end}}{{end}}`))
+
+// RenderHTML рендерит резюме в HTML для Telegram
+func (r *StructuredSummary) RenderHTML(participant bool, lang Language, links chatLinkInfo) (string, error) {
+	var buf bytes.Buffer
+	err := summaryHTMLTemplate.Execute(&buf, r.view(participant, lang, links))
+	return strings.TrimSpace(buf.String()), err
+}
+
+// RenderMarkdown рендерит резюме в Markdown
+func (r *StructuredSummary) RenderMarkdown(participant bool, lang Language, links chatLinkInfo) (string, error) {
+	var buf bytes.Buffer
+	err := summaryMarkdownTemplate.Execute(&buf, r.view(participant, lang, links))
+	return strings.TrimSpace(buf.String()), err
+}
+
+func (r *StructuredSummary) view(participant bool, lang Language, links chatLinkInfo) summaryView {
+	return summaryView{StructuredSummary: r, Participant: participant, L: lang.info().labels, links: links}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// testLinks публичный чат с сообщениями 100 и 101
+var testLinks = chatLinkInfo{chatID: -1001234, chatUsername: "chat", known: map[int]bool{100: true, 101: true}}
+
+const validSummaryJSON = `{
+  "topics": [
+    {"title": "Релиз", "emoji": "🚀", "description": "Обсудили <релиз> & сроки", "message_ids": [100, 999, 101]},
+    {"title": "  ", "emoji": "", "description": "", "message_ids": [100]}
+  ],
+  "links": [
+    {"url": "https://go.dev/doc", "description": "Документация"},
+    {"url": "javascript:alert(1)", "description": "плохая"},
+    {"url": "go.dev", "description": "без схемы"}
+  ],
+  "decisions": ["Выпускаем в пятницу", " "],
+  "positions": []
+}`
+
+func TestParseStructuredSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+		topics  int
+		ids     []int
+	}{
+		{"валидный", validSummaryJSON, false, 1, []int{100, 101}},
+		{"в markdown-блоке", "```json\n" + validSummaryJSON + "\n```", false, 1, []int{100, 101}},
+		{"с пояснением вокруг", "Вот резюме:\n" + validSummaryJSON + "\nГотово", false, 1, []int{100, 101}},
+		{"чужие номера сообщений", `{"topics": [{"title": "Тема", "message_ids": [1, 2, 3]}]}`, false, 1, []int{}},
+		{"без необязательных списков", `{"topics": [{"title": "Тема", "description": "о чем"}]}`, false, 1, []int{}},
+		{"не JSON", "Сегодня обсуждали релиз", true, 0, nil},
+		{"битый JSON", `{"topics": [{"title": "Тема",}]}`, true, 0, nil},
+		{"обрезанный JSON", `{"topics": [{"title": "Тема"`, true, 0, nil},
+		{"не тот тип", `{"topics": "Релиз"}`, true, 0, nil},
+		{"нет topics", `{"links": [], "decisions": [], "positions": []}`, true, 0, nil},
+		{"пустые topics", `{"topics": []}`, true, 0, nil},
+		{"темы без названия и описания", `{"topics": [{"title": "", "description": " ", "message_ids": [100]}]}`, true, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := parseStructuredSummary(tt.raw, testLinks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(summary.Topics) != tt.topics {
+				t.Fatalf("topics = %d, want %d", len(summary.Topics), tt.topics)
+			}
+			if got := summary.Topics[0].MessageIDs; !reflect.DeepEqual(got, tt.ids) {
+				t.Errorf("message_ids = %v, want %v", got, tt.ids)
+			}
+		})
+	}
+}
+
+func TestStructuredSummaryValidateLimits(t *testing.T) {
+	summary := StructuredSummary{}
+	for i := 0; i < maxSummaryTopics+3; i++ {
+		summary.Topics = append(summary.Topics, SummaryTopic{Title: "Тема"})
+	}
+	for i := 0; i < maxSummaryItems+3; i++ {
+		summary.Decisions = append(summary.Decisions, "решение")
+		summary.Links = append(summary.Links, SummaryLink{URL: "https://example.com"})
+	}
+
+	if err := summary.validate(testLinks); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Topics) != maxSummaryTopics || len(summary.Decisions) != maxSummaryItems || len(summary.Links) != maxSummaryItems {
+		t.Errorf("topics/decisions/links = %d/%d/%d, want %d/%d/%d",
+			len(summary.Topics), len(summary.Decisions), len(summary.Links), maxSummaryTopics, maxSummaryItems, maxSummaryItems)
+	}
+}
+
+func TestStructuredSummaryRender(t *testing.T) {
+	summary, err := parseStructuredSummary(validSummaryJSON, testLinks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := summary.RenderHTML(false, LangRU, testLinks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"🔥 <b>Главные темы:</b>",
+		"• 🚀 <b>Релиз</b> - Обсудили &lt;релиз&gt; &amp; сроки",
+		`<a href="https://t.me/chat/100">↗</a>`,
+		"✅ <b>Договорились:</b>\n• Выпускаем в пятницу",
+		`• <a href="https://go.dev/doc">Документация</a>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML has no %q:\n%s", want, html)
+		}
+	}
+	for _, unwanted := range []string{"javascript:", "Позиции", "без схемы"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("HTML has %q:\n%s", unwanted, html)
+		}
+	}
+
+	markdown, err := summary.RenderMarkdown(true, LangRU, testLinks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## О чем писал",
+		"- 🚀 **Релиз** — Обсудили <релиз> & сроки ([↗](https://t.me/chat/100))",
+		"## Договорились\n\n- Выпускаем в пятницу",
+		"- [Документация](https://go.dev/doc)",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown has no %q:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestParseOrRepairSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		repaired string
+		wantErr  bool
+		calls    int
+	}{
+		{"валидный без починки", validSummaryJSON, "", false, 0},
+		{"починили", `{"topics": [`, validSummaryJSON, false, 1},
+		{"починка тоже битая", `{"topics": [`, `{"topics": []}`, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+					Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+						Role:    openai.ChatMessageRoleAssistant,
+						Content: tt.repaired,
+					}}},
+				})
+			}))
+			defer srv.Close()
+
+			cfg := openai.DefaultConfig("test")
+			cfg.BaseURL = srv.URL
+			svc := NewSummaryService(nil, openai.NewClientWithConfig(cfg), "test-model", 1000, 1)
+
+			summary, err := svc.parseOrRepairSummary(tt.raw, testLinks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.calls {
+				t.Errorf("repair calls = %d, want %d", calls, tt.calls)
+			}
+			if err == nil && summary.Topics[0].Title != "Релиз" {
+				t.Errorf("topic = %q, want %q", summary.Topics[0].Title, "Релиз")
+			}
+		})
+	}
+}
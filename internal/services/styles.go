@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SummaryStyle стиль, в котором модель пишет резюме
+type SummaryStyle string
+
+const (
+	// StyleSlang - пацан с района, как было изначально
+	StyleSlang SummaryStyle = "slang"
+	// StyleNeutral - спокойный нейтральный пересказ
+	StyleNeutral SummaryStyle = "neutral"
+	// StyleBusiness - деловой тон для рабочих чатов
+	StyleBusiness SummaryStyle = "business"
+	// StyleBullets - только короткие пункты без пересказа
+	StyleBullets SummaryStyle = "bullets"
+)
+
+// DefaultSummaryStyle стиль чата, в котором ничего не выбирали
+const DefaultSummaryStyle = StyleSlang
+
+// SummaryStyles все стили в порядке показа
+var SummaryStyles = []SummaryStyle{StyleSlang, StyleNeutral, StyleBusiness, StyleBullets}
+
+// summaryStyleInfo куски промпта и описание стиля
+type summaryStyleInfo struct {
+	title string
+	// aliases как стиль можно назвать в команде
+	aliases []string
+	// persona первая строка промпта резюме чата, participantPersona - резюме участника
+	persona            string
+	participantPersona string
+	// voice раздел "Твой стиль"
+	voice string
+	// tasks дополнительные пункты к разделу "Что ты делаешь"
+	tasks string
+}
+
+var summaryStyles = map[SummaryStyle]summaryStyleInfo{
+	StyleSlang: {
+		title:              "пацанский 😎",
+		aliases:            []string{"сленг", "пацанский", "братан"},
+		persona:            "Ты крутой пацан с района, который умеет анализировать чатики и делать огненные резюме для корешей.",
+		participantPersona: "Ты крутой пацан с района, который рассказывает корешам, о чем писал конкретный человек в чате.",
+		voice: `- Говоришь как настоящий братан - простым языком, с прикольными фразочками
+- Используешь сленг: "братан", "чел", "тема", "движ", "кайф", "жесть" и т.д.
+- Пишешь живо и интересно, как будто рассказываешь корешу что было
+- Если что-то скучное - честно говоришь об этом`,
+	},
+	StyleNeutral: {
+		title:              "нейтральный 🙂",
+		aliases:            []string{"нейтральный", "обычный", "normal"},
+		persona:            "Ты аккуратно пересказываешь, что происходило в групповом чате.",
+		participantPersona: "Ты аккуратно пересказываешь, о чем писал конкретный участник группового чата.",
+		voice: `- Пишешь простым спокойным языком, без сленга и оценок
+- Не шутишь и не подкалываешь участников
+- Передаешь суть так, чтобы было понятно тому, кто пропустил обсуждение`,
+	},
+	StyleBusiness: {
+		title:              "деловой 💼",
+		aliases:            []string{"деловой", "рабочий", "formal", "work"},
+		persona:            "Ты ассистент, который готовит деловые сводки по рабочему чату.",
+		participantPersona: "Ты ассистент, который готовит деловую сводку по сообщениям одного сотрудника в рабочем чате.",
+		voice: `- Пишешь в деловом тоне: четко, без сленга, эмоций и шуток
+- Формулируешь как в протоколе встречи: что обсуждали, что решили, что осталось открытым
+- Называешь участников по именам, когда важно, кто что предложил`,
+		tasks: `- В "decisions" обязательно выноси договоренности, сроки и ответственных, если они звучали
+- В описании темы отмечай, если вопрос остался нерешенным`,
+	},
+	StyleBullets: {
+		title:              "только пункты 📌",
+		aliases:            []string{"пункты", "список", "кратко", "bullet", "bullets"},
+		persona:            "Ты делаешь максимально сжатые сводки группового чата списком пунктов.",
+		participantPersona: "Ты делаешь максимально сжатую сводку сообщений одного участника чата списком пунктов.",
+		voice: `- Никаких вступлений, пересказа и оценок - только факты
+- Каждый пункт - не длиннее 10-12 слов
+- Без сленга и шуток`,
+		tasks: `- "description" темы - одна короткая фраза, можно пустая строка, если все понятно из названия
+- "emoji" оставляй пустой строкой`,
+	},
+}
+
+// ParseSummaryStyle находит стиль по названию или его синониму
+func ParseSummaryStyle(value string) (SummaryStyle, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, style := range SummaryStyles {
+		if string(style) == value {
+			return style, true
+		}
+		for _, alias := range summaryStyles[style].aliases {
+			if alias == value {
+				return style, true
+			}
+		}
+	}
+	return "", false
+}
+
+// OrDefault возвращает сам стиль или стиль по умолчанию, если он пустой или неизвестный
+func (st SummaryStyle) OrDefault() SummaryStyle {
+	if _, ok := summaryStyles[st]; ok {
+		return st
+	}
+	return DefaultSummaryStyle
+}
+
+func (st SummaryStyle) info() summaryStyleInfo {
+	return summaryStyles[st.OrDefault()]
+}
+
+// Title человекочитаемое название стиля
+func (st SummaryStyle) Title() string {
+	return st.info().title
+}
+
+// systemPrompt собирает системный промпт резюме под стиль
+func (st SummaryStyle) systemPrompt(participant bool) string {
+	info := st.info()
+	if participant {
+		return fmt.Sprintf(participantSystemPrompt, info.participantPersona, info.voice, withLeadingNewline(info.tasks)) + summaryJSONFormat
+	}
+	return fmt.Sprintf(summarySystemPrompt, info.persona, info.voice, withLeadingNewline(info.tasks)) + summaryJSONFormat
+}
+
+func withLeadingNewline(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "\n" + text
+}
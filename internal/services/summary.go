@@ -3,7 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
-	"strings"
+	"log"
 	"summarybot/internal/database"
 	"time"
 
@@ -11,132 +11,339 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	// summaryRefreshMessages сколько новых сообщений нужно, чтобы пересобрать резюме за незакрытый период
+	summaryRefreshMessages = 15
+	// minUserMessagesForAI минимум сообщений участника для резюме по одному человеку
+	minUserMessagesForAI = 5
+)
+
+// SummaryRequest параметры запроса резюме
+type SummaryRequest struct {
+	ChatID int64
+	Period Period
+	// UserID - резюме только по сообщениям одного участника (0 - весь чат)
+	UserID   int64
+	UserName string
+	// TopicID - резюме только одной темы форума (0 - весь чат, темы подписываются)
+	TopicID int
+	// ThreadRootID - резюме одной ветки ответов с этим корнем (0 - все сообщения за период)
+	ThreadRootID int
+	// Force - сгенерировать заново, даже если есть сохраненное резюме
+	Force bool
+	// Style - стиль резюме, пустой - стиль по умолчанию
+	Style SummaryStyle
+	// Language - язык резюме, пустой - язык по умолчанию
+	Language Language
+	// Detail - подробность: -1 короче, 0 обычно, 1 подробнее
+	Detail int
+}
+
+// SummaryResult готовое резюме. ID - запись ChatSummary, 0 если резюме не сохранялось
+// (например, когда сообщений слишком мало)
+type SummaryResult struct {
+	ID   uint
+	Text string
+}
+
+// summaryPrompt задача для модели: системный промпт и формулировка запроса
+type summaryPrompt struct {
+	system string
+	task   string
+}
+
 type SummaryService struct {
 	db               *gorm.DB
 	ai               *openai.Client
 	model            string
+	maxTokens        int
 	minMessagesForAI int
 }
 
-func NewSummaryService(db *gorm.DB, ai *openai.Client, model string, minMessages int) *SummaryService {
+func NewSummaryService(db *gorm.DB, ai *openai.Client, model string, maxTokens, minMessages int) *SummaryService {
 	return &SummaryService{
 		db:               db,
 		ai:               ai,
 		model:            model,
+		maxTokens:        maxTokens,
 		minMessagesForAI: minMessages,
 	}
 }
 
-func (s *SummaryService) GenerateSummary(chatID int64, days int) (string, error) {
-	messages, err := s.getMessagesForPeriod(chatID, days)
+// GenerateSummary делает резюме сообщений чата (или одного участника) за указанный период.
+// Сохраненное резюме переиспользуется: за прошедший период - всегда,
+// за текущий - пока не накопится summaryRefreshMessages новых сообщений
+func (s *SummaryService) GenerateSummary(req SummaryRequest) (SummaryResult, error) {
+	p := req.Period
+
+	if !req.Force && p.Cacheable() {
+		if cached, ok := s.findCachedSummary(req); ok {
+			return SummaryResult{ID: cached.ID, Text: cached.Summary}, nil
+		}
+	}
+
+	messages, err := s.getMessages(req)
 	if err != nil {
-		return "", err
+		return SummaryResult{}, err
 	}
 
-	period := s.getPeriodName(days)
+	texts := req.Language.info().texts
+	period := p.NameIn(req.Language)
+
+	minMessages := s.minMessagesForAI
+	if req.UserID != 0 {
+		minMessages = minUserMessagesForAI
+	}
+	if req.ThreadRootID != 0 {
+		minMessages = minThreadMessagesForAI
+	}
 
 	if len(messages) == 0 {
-		return fmt.Sprintf("За %s никто ничего не писал, братан 🤷‍♂️", period), nil
+		if req.UserID != 0 {
+			return SummaryResult{Text: fmt.Sprintf(texts.noUserMessages, period, req.UserName)}, nil
+		}
+		return SummaryResult{Text: fmt.Sprintf(texts.noMessages, period)}, nil
+	}
+
+	if len(messages) < minMessages {
+		return SummaryResult{Text: fmt.Sprintf(texts.tooFew, period, len(messages), minMessages)}, nil
+	}
+
+	timeLayout := "15:04"
+	if p.End.Sub(p.Start) > 24*time.Hour {
+		timeLayout = "02.01 15:04"
 	}
 
-	if len(messages) < s.minMessagesForAI {
-		return fmt.Sprintf("За %s было всего %d сообщений - слишком мало для нормального резюме, братан 📱\n\n"+
-			"Попробуй запросить резюме когда народ побольше пообщается! (нужно минимум %d сообщений)",
-			period, len(messages), s.minMessagesForAI), nil
+	links := newChatLinkInfo(req.ChatID, messages)
+	withRefs := links.hasRefs()
+
+	// В треде помечаем, кому отвечает автор, чтобы модель видела структуру ветки
+	authors := make(map[int]string)
+	// В резюме всего форума помечаем тему каждого сообщения
+	var topics map[int]string
+	if req.TopicID == 0 && req.ThreadRootID == 0 {
+		topics = s.topicLabels(req.ChatID, messages, req.Language)
 	}
 
-	var textBuilder strings.Builder
+	lines := make([]string, 0, len(messages))
 	for _, msg := range messages {
-		displayName := msg.FirstName
-		if displayName == "" {
-			displayName = msg.Username
+		stamp := msg.Timestamp.In(p.Location()).Format(timeLayout)
+		if withRefs && msg.TelegramMessageID != 0 {
+			stamp = fmt.Sprintf("#%d %s", msg.TelegramMessageID, stamp)
+		}
+		author := authorName(msg)
+		if parent, ok := authors[msg.ReplyToMessageID]; ok && req.ThreadRootID != 0 {
+			author += " → " + parent
+		}
+		authors[msg.TelegramMessageID] = authorName(msg)
+		if topics != nil {
+			author = fmt.Sprintf("[%s] %s", topics[msg.TopicID], author)
 		}
-		textBuilder.WriteString(fmt.Sprintf("[%s] %s: %s\n",
-			msg.Timestamp.Format("15:04"), displayName, msg.Text))
+		lines = append(lines, fmt.Sprintf("[%s] %s: %s\n", stamp, author, messageText(msg, req.Language)))
+	}
+
+	prompt := s.promptFor(req)
+	if topics != nil {
+		prompt.system += forumTopicsInstruction
+	}
+	if withRefs {
+		prompt.system += jumpLinksInstruction
+	}
+
+	failed := SummaryResult{Text: texts.failed}
+
+	raw, err := s.summarizeTranscript(lines, prompt, len(messages))
+	if err != nil {
+		return failed, err
 	}
 
-	summary, err := s.generateAISummary(textBuilder.String(), period, len(messages))
+	structured, err := s.parseOrRepairSummary(raw, links)
 	if err != nil {
-		return "Не смог замутить резюме, братан 😞", err
+		return failed, err
 	}
 
-	s.saveSummary(chatID, days, summary)
+	summary, err := structured.RenderHTML(req.UserID != 0, req.Language, links)
+	if err != nil {
+		return failed, err
+	}
 
-	return summary, nil
+	// Сохраняем всегда: даже если период не переиспользуется, по записи работают кнопки под резюме
+	id := s.saveSummary(req, summary, structured, messages)
+
+	return SummaryResult{ID: id, Text: summary}, nil
 }
 
-func (s *SummaryService) getMessagesForPeriod(chatID int64, days int) ([]database.Message, error) {
-	var messages []database.Message
-	startDate := time.Now().AddDate(0, 0, -days).Truncate(24 * time.Hour)
-	endDate := startDate.Add(24 * time.Hour)
+// FindParticipant ищет участника чата по username (без @)
+func (s *SummaryService) FindParticipant(chatID int64, username string) (int64, string, bool) {
+	var msg database.Message
+	err := s.db.Where("chat_id = ? AND LOWER(username) = LOWER(?)", chatID, username).
+		Order("timestamp DESC").
+		First(&msg).Error
+	if err != nil {
+		return 0, "", false
+	}
 
-	err := s.db.Where("chat_id = ? AND timestamp >= ? AND timestamp < ?",
-		chatID, startDate, endDate).
-		Order("timestamp ASC").
-		Find(&messages).Error
+	return msg.UserID, authorName(msg), true
+}
 
-	return messages, err
+// LastUserMessageTime возвращает время последнего сообщения пользователя в чате до момента before
+func (s *SummaryService) LastUserMessageTime(chatID, userID int64, before time.Time) (time.Time, bool) {
+	var msg database.Message
+	err := s.db.Where("chat_id = ? AND user_id = ? AND timestamp < ?",
+		chatID, userID, before.UTC()).
+		Order("timestamp DESC").
+		First(&msg).Error
+	if err != nil {
+		return time.Time{}, false
+	}
+	return msg.Timestamp, true
 }
 
-func (s *SummaryService) getPeriodName(days int) string {
-	switch days {
-	case 0:
-		return "сегодня"
-	case 1:
-		return "вчера"
-	case 2:
-		return "позавчера"
-	default:
-		return fmt.Sprintf("%d дней назад", days)
+// findCachedSummary ищет сохраненное резюме за период, которое еще можно отдать
+func (s *SummaryService) findCachedSummary(req SummaryRequest) (*database.ChatSummary, bool) {
+	p := req.Period
+	start, end := p.Bounds()
+
+	var cached database.ChatSummary
+	err := s.db.Where("chat_id = ? AND user_id = ? AND topic_id = ? AND period_kind = ? AND date = ? AND period_end = ? AND model = ? AND style = ? AND language = ? AND detail = ?",
+		req.ChatID, req.UserID, req.TopicID, string(p.Kind), start, end, s.model,
+		string(req.Style.OrDefault()), string(req.Language.OrDefault()), req.Detail).
+		Order("created_at DESC").
+		First(&cached).Error
+	if err != nil {
+		return nil, false
+	}
+
+	// Период закрыт - новых сообщений уже не будет
+	if !p.End.After(time.Now()) {
+		return &cached, true
 	}
+
+	var newMessages int64
+	s.messagesQuery(req).
+		Where("timestamp > ?", cached.LastMessageAt.UTC()).
+		Count(&newMessages)
+
+	if newMessages >= summaryRefreshMessages {
+		log.Printf("Резюме чата %d за %s устарело: %d новых сообщений", req.ChatID, p.Name(), newMessages)
+		return nil, false
+	}
+
+	return &cached, true
 }
 
-func (s *SummaryService) generateAISummary(messages, period string, count int) (string, error) {
-	systemPrompt := `Ты крутой пацан с района, который умеет анализировать чатики и делать огненные резюме для корешей.
+// CountMessages возвращает количество сообщений, попадающих в запрос резюме
+func (s *SummaryService) CountMessages(req SummaryRequest) int64 {
+	if req.ThreadRootID != 0 {
+		messages, _ := s.threadMessages(req.ChatID, req.ThreadRootID)
+		return int64(len(messages))
+	}
 
-ВАЖНО - АНАЛИЗИРУЙ ТОЛЬКО РЕАЛЬНЫЕ СООБЩЕНИЯ:
-- Пересказывай ТОЛЬКО то, что реально было написано в чате
-- НЕ выдумывай события, имена, темы которых не было
-- Если сообщений мало или они скучные - честно говори об этом
-- Точно передавай факты, но своими словами в классном стиле
-- НИКОГДА НЕ ПОВТОРЯЙ одну и ту же информацию в разных секциях!
+	var count int64
+	s.messagesQuery(req).Count(&count)
+	return count
+}
 
-Твой стиль:
-- Говоришь как настоящий братан - простым языком, с прикольными фразочками
-- Используешь сленг: "братан", "чел", "тема", "движ", "кайф", "жесть" и т.д.
-- Эмодзи ставишь к месту, но не переборщиваешь
-- Пишешь живо и интересно, как будто рассказываешь корешу что было
-- Если что-то скучное - честно говоришь об этом
+// messagesQuery выборка сообщений чата (участника, темы форума) за период запроса
+func (s *SummaryService) messagesQuery(req SummaryRequest) *gorm.DB {
+	start, end := req.Period.Bounds()
+	query := s.db.Model(&database.Message{}).
+		Where("chat_id = ? AND timestamp >= ? AND timestamp < ?", req.ChatID, start, end)
+	if req.UserID != 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.TopicID != 0 {
+		query = query.Where("topic_id = ?", req.TopicID)
+	}
+	return query
+}
 
-Что ты делаешь:
-- Выделяешь 4-8 РАЗНЫХ тем/событий ИЗ РЕАЛЬНЫХ СООБЩЕНИЙ
-- Каждая тема должна быть УНИКАЛЬНОЙ - не повторяй информацию!
-- Группируешь связанные сообщения, но не дублируй их в разных секциях
-- Используешь HTML теги: <b>жирный</b>, <i>курсив</i>
-- Пишешь 1-2 предложения на тему, коротко и по делу
+// Messages возвращает сообщения, попадающие в запрос резюме, по порядку времени
+func (s *SummaryService) Messages(req SummaryRequest) ([]database.Message, error) {
+	return s.getMessages(req)
+}
 
-НОВЫЙ упрощенный формат (БЕЗ ПОВТОРОВ!):
+func (s *SummaryService) getMessages(req SummaryRequest) ([]database.Message, error) {
+	if req.ThreadRootID != 0 {
+		return s.threadMessages(req.ChatID, req.ThreadRootID)
+	}
 
-🔥 <b>Главные темы дня:</b>
-• [тема 1 с эмодзи] - описание
-• [тема 2 с эмодзи] - описание  
-• [тема 3 с эмодзи] - описание
-• [тема 4 с эмодзи] - описание (если есть)
+	var messages []database.Message
+	err := s.messagesQuery(req).
+		Order("timestamp ASC").
+		Find(&messages).Error
 
-📍 <b>Полезняк:</b> (только если реально есть ссылки/важная инфа)
-• [ссылка или важное решение]
+	return messages, err
+}
 
-Главное - каждая тема должна быть РАЗНОЙ! Не повторяй одно и то же!`
+func (s *SummaryService) getPeriodName(p Period) string {
+	return p.Name()
+}
 
-	userPrompt := fmt.Sprintf(`Проанализируй ВСЕ сообщения ниже и сделай резюме за %s. 
+// promptFor собирает задачу для модели под конкретный запрос
+func (s *SummaryService) promptFor(req SummaryRequest) summaryPrompt {
+	period := s.getPeriodName(req.Period)
+
+	var prompt summaryPrompt
+	if req.ThreadRootID != 0 {
+		prompt = summaryPrompt{
+			system: req.Style.systemPrompt(false),
+			task: "Ниже ВСЕ сообщения одной ветки ответов (треда). Запись \"Автор → Имя\" значит, что автор отвечает этому участнику. " +
+				"Сделай резюме этого обсуждения: с чего началось, кто что отвечал и чем закончилось.",
+		}
+	} else if req.UserID != 0 {
+		prompt = summaryPrompt{
+			system: req.Style.systemPrompt(true),
+			task: fmt.Sprintf("Ниже ВСЕ сообщения участника %s за %s. "+
+				"Расскажи, о чем он писал, что его волновало и какие позиции он занимал.", req.UserName, period),
+		}
+	} else {
+		prompt = summaryPrompt{
+			system: req.Style.systemPrompt(false),
+			task:   fmt.Sprintf("Проанализируй ВСЕ сообщения ниже и сделай резюме за %s.", period),
+		}
+	}
+
+	if req.Period.Short() {
+		prompt.system += shortWindowInstruction
+	}
+	switch {
+	case req.Detail > 0:
+		prompt.system += moreDetailInstruction
+	case req.Detail < 0:
+		prompt.system += shorterInstruction
+	}
+	prompt.system += req.Language.info().instruction
+
+	return prompt
+}
+
+func (s *SummaryService) generateAISummary(messages string, prompt summaryPrompt, count int) (string, error) {
+	userPrompt := fmt.Sprintf(`%s
 
 ВАЖНО: Анализируй ТОЛЬКО эти сообщения, не выдумывай ничего лишнего!
 
 Всего сообщений для анализа: %d
 
 Сообщения:
-%s`, period, count, messages)
+%s`, prompt.task, count, messages)
 
+	return s.completeJSON(prompt.system, userPrompt, s.maxTokens)
+}
+
+// complete отправляет запрос к модели и возвращает текст ответа
+func (s *SummaryService) complete(systemPrompt, userPrompt string, maxTokens int) (string, error) {
+	return s.chat(systemPrompt, userPrompt, maxTokens, nil)
+}
+
+// completeJSON как complete, но просит модель ответить JSON-объектом
+func (s *SummaryService) completeJSON(systemPrompt, userPrompt string, maxTokens int) (string, error) {
+	return s.chat(systemPrompt, userPrompt, maxTokens, &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+	})
+}
+
+func (s *SummaryService) chat(systemPrompt, userPrompt string, maxTokens int, format *openai.ChatCompletionResponseFormat) (string, error) {
 	resp, err := s.ai.CreateChatCompletion(
 		context.Background(),
 		openai.ChatCompletionRequest{
@@ -151,8 +358,9 @@ func (s *SummaryService) generateAISummary(messages, period string, count int) (
 					Content: userPrompt,
 				},
 			},
-			MaxTokens:   1200,
-			Temperature: 0.3,
+			MaxTokens:      maxTokens,
+			Temperature:    0.3,
+			ResponseFormat: format,
 		},
 	)
 
@@ -167,12 +375,144 @@ func (s *SummaryService) generateAISummary(messages, period string, count int) (
 	return resp.Choices[0].Message.Content, nil
 }
 
-func (s *SummaryService) saveSummary(chatID int64, days int, summary string) {
+// saveSummary сохраняет резюме и возвращает ID записи (0, если сохранить не удалось)
+func (s *SummaryService) saveSummary(req SummaryRequest, summary string, structured *StructuredSummary, messages []database.Message) uint {
+	p := req.Period
+	start, end := p.Bounds()
 	chatSummary := database.ChatSummary{
-		ChatID:    chatID,
-		Date:      time.Now().AddDate(0, 0, -days).Truncate(24 * time.Hour),
-		Summary:   summary,
-		CreatedAt: time.Now(),
+		ChatID:         req.ChatID,
+		UserID:         req.UserID,
+		UserName:       req.UserName,
+		Date:           start,
+		PeriodKind:     string(p.Kind),
+		PeriodEnd:      end,
+		PeriodDays:     p.Days,
+		PeriodHours:    p.Hours,
+		PeriodExplicit: p.Explicit,
+		ThreadRootID:   req.ThreadRootID,
+		TopicID:        req.TopicID,
+		MessageCount:   len(messages),
+		Model:          s.model,
+		Style:          string(req.Style.OrDefault()),
+		Language:       string(req.Language.OrDefault()),
+		Detail:         req.Detail,
+		LastMessageAt:  messages[len(messages)-1].Timestamp.UTC(),
+		Summary:        summary,
+		Structured:     structured.JSON(),
+		CreatedAt:      time.Now(),
+	}
+	if err := s.db.Create(&chatSummary).Error; err != nil {
+		log.Printf("Ошибка сохранения резюме: %v", err)
+		return 0
+	}
+	return chatSummary.ID
+}
+
+// StoredSummary возвращает сохраненное резюме и запрос, по которому оно было сделано.
+// Период восстанавливается по тем же границам, в таймзоне loc
+func (s *SummaryService) StoredSummary(id uint, loc *time.Location) (*database.ChatSummary, SummaryRequest, error) {
+	var cs database.ChatSummary
+	if err := s.db.First(&cs, id).Error; err != nil {
+		return nil, SummaryRequest{}, err
+	}
+
+	period := Period{
+		Kind:     PeriodKind(cs.PeriodKind),
+		Days:     cs.PeriodDays,
+		Hours:    cs.PeriodHours,
+		Start:    cs.Date.In(loc),
+		End:      cs.PeriodEnd.In(loc),
+		Explicit: cs.PeriodExplicit,
 	}
-	s.db.Create(&chatSummary)
+	if period.Kind == PeriodDay {
+		// "вчера" через сутки становится "позавчера"
+		today := dayStart(time.Now().In(loc))
+		period.Days = int(today.Sub(dayStart(period.Start)).Hours()+12) / 24
+	}
+
+	return &cs, SummaryRequest{
+		ChatID:       cs.ChatID,
+		Period:       period,
+		UserID:       cs.UserID,
+		UserName:     cs.UserName,
+		TopicID:      cs.TopicID,
+		ThreadRootID: cs.ThreadRootID,
+		Style:        SummaryStyle(cs.Style),
+		Language:     Language(cs.Language),
+		Detail:       cs.Detail,
+	}, nil
 }
+
+// summarySystemPrompt системный промпт для итогового резюме: персона, стиль и доп. задачи берутся из SummaryStyle
+const summarySystemPrompt = `%s
+
+ВАЖНО - АНАЛИЗИРУЙ ТОЛЬКО РЕАЛЬНЫЕ СООБЩЕНИЯ:
+- Пересказывай ТОЛЬКО то, что реально было написано в чате
+- НЕ выдумывай события, имена, темы которых не было
+- Если сообщений мало или они скучные - честно говори об этом
+- Точно передавай факты, но своими словами
+- НИКОГДА НЕ ПОВТОРЯЙ одну и ту же информацию в разных секциях!
+
+Твой стиль:
+%s
+
+Что ты делаешь:
+- Выделяешь 4-8 РАЗНЫХ тем/событий ИЗ РЕАЛЬНЫХ СООБЩЕНИЙ
+- Каждая тема должна быть УНИКАЛЬНОЙ - не повторяй информацию!
+- Группируешь связанные сообщения, но не дублируй их в разных темах
+- Пишешь 1-2 предложения на тему, коротко и по делу
+- В "links" - только реальные ссылки из сообщений, в "decisions" - только реальные договоренности
+- Поле "positions" оставляй пустым%s
+
+Главное - каждая тема должна быть РАЗНОЙ! Не повторяй одно и то же!`
+
+// shortWindowInstruction дописывается к системному промпту, когда резюме просят за несколько часов
+const shortWindowInstruction = `
+
+КОРОТКИЙ ОТРЕЗОК:
+- Это не целый день, а несколько часов живого обсуждения - не пиши "за день", "сегодня обсуждали" и т.п.
+- Тем может быть 1-3, не дроби одно обсуждение на несколько тем ради количества
+- Обсуждение могло еще не закончиться - так и говори, если к итогу не пришли`
+
+// forumTopicsInstruction дописывается к системному промпту, когда в резюме всего чата попали разные темы форума
+const forumTopicsInstruction = `
+
+ТЕМЫ ФОРУМА:
+- Чат разбит на темы форума, название темы каждого сообщения - в квадратных скобках после времени
+- Не смешивай обсуждения из разных тем форума в одну тему резюме
+- Начинай название каждой темы резюме с названия темы форума в квадратных скобках, например "[Работа] Дедлайн по релизу"
+- Темы резюме из одной темы форума ставь подряд`
+
+// moreDetailInstruction и shorterInstruction дописываются к промпту по кнопкам "подробнее" и "короче"
+const (
+	moreDetailInstruction = `
+
+ПОДРОБНЕЕ:
+- Это повторный запрос - прошлое резюме показалось слишком коротким
+- Можно до 10 тем, в описании темы 3-4 предложения: кто что предлагал, какие были аргументы, чем закончилось
+- Не пропускай второстепенные, но реальные обсуждения`
+	shorterInstruction = `
+
+КОРОЧЕ:
+- Это повторный запрос - прошлое резюме показалось слишком длинным
+- Только 2-4 самые важные темы, в описании одно короткое предложение
+- "positions" оставь пустым, в "decisions" и "links" - только самое важное`
+)
+
+// participantSystemPrompt системный промпт для резюме по одному участнику, собирается так же, как summarySystemPrompt
+const participantSystemPrompt = `%s
+
+ВАЖНО - АНАЛИЗИРУЙ ТОЛЬКО РЕАЛЬНЫЕ СООБЩЕНИЯ:
+- Тебе дают сообщения ТОЛЬКО одного человека, реплик остальных не видно
+- Пересказывай ТОЛЬКО то, что он реально написал
+- НЕ выдумывай, с кем он спорил и что ему отвечали
+- Если сообщения скучные или бессвязные - честно говори об этом
+
+Твой стиль:
+%s
+
+Что ты делаешь:
+- В "topics" - 3-6 РАЗНЫХ тем, о которых он писал, 1-2 предложения на тему
+- В "positions" - за что он топил или против чего выступал (только если реально что-то отстаивал)
+- В "links" - ссылки, которые он кидал
+- В "decisions" - договоренности, которые он предлагал или принял%s`
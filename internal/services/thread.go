@@ -0,0 +1,84 @@
+package services
+
+import (
+	"sort"
+	"summarybot/internal/database"
+	"time"
+)
+
+const (
+	// maxThreadMessages сколько сообщений ветки ответов берем в резюме
+	maxThreadMessages = 500
+	// maxThreadDepth защита от зацикливания при подъеме к корню ветки
+	maxThreadDepth = 200
+	// minThreadMessagesForAI минимум сообщений для резюме треда - ветки обычно короче дня в чате
+	minThreadMessagesForAI = 3
+)
+
+// FindReplyThread находит ветку ответов, в которую входит сообщение messageID:
+// поднимается по ответам до корня и возвращает ID корня и период от первого до последнего сообщения ветки
+func (s *SummaryService) FindReplyThread(chatID int64, messageID int, loc *time.Location) (int, Period, bool) {
+	rootID := s.threadRoot(chatID, messageID)
+
+	messages, err := s.threadMessages(chatID, rootID)
+	if err != nil || len(messages) == 0 {
+		return 0, Period{}, false
+	}
+
+	return rootID, ThreadPeriod(messages[0].Timestamp, messages[len(messages)-1].Timestamp, loc), true
+}
+
+// threadRoot поднимается по цепочке ответов от messageID до самого верхнего сообщения.
+// Если родителя нет в БД (написан до добавления бота), корнем считается его ID -
+// тогда в ветку попадут все ответы на него
+func (s *SummaryService) threadRoot(chatID int64, messageID int) int {
+	rootID := messageID
+	for i := 0; i < maxThreadDepth; i++ {
+		var msg database.Message
+		err := s.db.Select("reply_to_message_id").
+			Where("chat_id = ? AND telegram_message_id = ?", chatID, rootID).
+			First(&msg).Error
+		if err != nil || msg.ReplyToMessageID == 0 {
+			break
+		}
+		rootID = msg.ReplyToMessageID
+	}
+	return rootID
+}
+
+// threadMessages собирает корень ветки и все ответы на него по всем уровням, по порядку времени
+func (s *SummaryService) threadMessages(chatID int64, rootID int) ([]database.Message, error) {
+	var messages []database.Message
+	if err := s.db.Where("chat_id = ? AND telegram_message_id = ?", chatID, rootID).
+		Limit(1).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	seen := map[int]bool{rootID: true}
+	frontier := []int{rootID}
+	for len(frontier) > 0 && len(messages) < maxThreadMessages {
+		var replies []database.Message
+		err := s.db.Where("chat_id = ? AND reply_to_message_id IN ?", chatID, frontier).
+			Order("timestamp ASC").
+			Limit(maxThreadMessages - len(messages)).
+			Find(&replies).Error
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = frontier[:0]
+		for _, reply := range replies {
+			if seen[reply.TelegramMessageID] {
+				continue
+			}
+			seen[reply.TelegramMessageID] = true
+			messages = append(messages, reply)
+			frontier = append(frontier, reply.TelegramMessageID)
+		}
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Timestamp.Before(messages[j].Timestamp)
+	})
+	return messages, nil
+}
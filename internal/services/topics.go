@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"summarybot/internal/database"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SaveTopicName запоминает название темы форума, чтобы подписывать темы в резюме всего чата
+func (s *SummaryService) SaveTopicName(chatID int64, topicID int, name string) error {
+	if topicID == 0 || name == "" {
+		return nil
+	}
+
+	var topic database.ForumTopic
+	err := s.db.Where("chat_id = ? AND topic_id = ?", chatID, topicID).
+		Limit(1).Find(&topic).Error
+	if err != nil {
+		return err
+	}
+
+	topic.ChatID, topic.TopicID, topic.Name, topic.UpdatedAt = chatID, topicID, name, time.Now()
+	return s.db.Save(&topic).Error
+}
+
+// SaveTopicNameIfMissing запоминает название темы, только если о ней еще ничего не известно.
+// Обычные сообщения темы отвечают на ее создание с исходным названием - оно не должно
+// затирать переименование, сохраненное через SaveTopicName
+func (s *SummaryService) SaveTopicNameIfMissing(chatID int64, topicID int, name string) error {
+	if topicID == 0 || name == "" {
+		return nil
+	}
+
+	var count int64
+	err := s.db.Model(&database.ForumTopic{}).
+		Where("chat_id = ? AND topic_id = ?", chatID, topicID).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+
+	topic := database.ForumTopic{ChatID: chatID, TopicID: topicID, Name: name, UpdatedAt: time.Now()}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&topic).Error
+}
+
+// topicLabels подписи тем форума для сообщений резюме всего чата.
+// Если все сообщения из одной темы (или чат не форум), подписывать нечего - возвращает nil
+func (s *SummaryService) topicLabels(chatID int64, messages []database.Message, lang Language) map[int]string {
+	topics := make(map[int]bool)
+	for _, msg := range messages {
+		topics[msg.TopicID] = true
+	}
+	if len(topics) < 2 {
+		return nil
+	}
+
+	var known []database.ForumTopic
+	s.db.Where("chat_id = ?", chatID).Find(&known)
+	names := make(map[int]string, len(known))
+	for _, topic := range known {
+		names[topic.TopicID] = topic.Name
+	}
+
+	texts := lang.info().texts
+	labels := make(map[int]string, len(topics))
+	for id := range topics {
+		switch {
+		case id == 0:
+			labels[id] = texts.generalTopic
+		case names[id] != "":
+			labels[id] = names[id]
+		default:
+			labels[id] = fmt.Sprintf(texts.unnamedTopic, id)
+		}
+	}
+	return labels
+}
@@ -2,7 +2,11 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"gopkg.in/telebot.v3"
 )
@@ -58,6 +62,26 @@ func TrimBotUsername(text, botUsername string) string {
 	return strings.TrimSpace(cleanText)
 }
 
+// MessageLink возвращает ссылку на сообщение в чате или пустую строку, если ссылку не построить.
+// Публичные чаты - t.me/username/id, приватные супергруппы - t.me/c/<id без -100>/id
+func MessageLink(chatID int64, chatUsername string, messageID int) string {
+	if messageID <= 0 {
+		return ""
+	}
+
+	if chatUsername != "" {
+		return fmt.Sprintf("https://t.me/%s/%d", chatUsername, messageID)
+	}
+
+	id := strconv.FormatInt(chatID, 10)
+	if !strings.HasPrefix(id, "-100") {
+		// У обычных групп ссылок на сообщения нет
+		return ""
+	}
+
+	return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(id, "-100"), messageID)
+}
+
 // GenerateThreadID создает уникальный ID для диалога
 func GenerateThreadID(chatID, userID int64, timestamp int64) string {
 	return fmt.Sprintf("%d_%d_%d", chatID, userID, timestamp)
@@ -114,14 +138,167 @@ func IsProvocativeMessage(text string) bool {
 	return false
 }
 
+// IsCatchUpRequest проверяет, просит ли пользователь рассказать, что он пропустил
+func IsCatchUpRequest(text string) bool {
+	cleanText := strings.ToLower(text)
+
+	catchUpTriggers := []string{
+		"что я пропустил", "что я пропустила", "что пропустил", "что пропустила",
+		"что я упустил", "что я упустила", "что нового пока меня не было",
+		"пока меня не было", "what did i miss",
+	}
+
+	for _, trigger := range catchUpTriggers {
+		if strings.Contains(cleanText, trigger) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsExportRequest проверяет, просят ли выгрузить резюме с перепиской файлом:
+// "экспорт", "выгрузи переписку", "экспорт за неделю в html". Одного слова "экспорт"
+// мало - "как сделать экспорт в csv?" это вопрос, а не запрос выгрузки
+func IsExportRequest(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(mentionRe.ReplaceAllString(text, " ")), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return false
+	}
+
+	exportVerbs := map[string]bool{
+		"экспорт": true, "экспортируй": true, "выгрузи": true, "выгрузка": true, "выгрузку": true, "export": true,
+	}
+	exportObjects := map[string]bool{
+		"переписку": true, "переписки": true, "чат": true, "чата": true, "резюме": true,
+		"chat": true, "transcript": true, "history": true, "summary": true, "html": true, "markdown": true, "md": true,
+	}
+
+	// Команда целиком: "экспорт", "выгрузи переписку", "export html"
+	if exportVerbs[words[0]] && (len(words) == 1 || exportObjects[words[1]]) {
+		return true
+	}
+
+	// Слово выгрузки где угодно, но вместе с периодом: "экспорт за неделю", "сделай выгрузку за вчера"
+	for _, word := range words {
+		if exportVerbs[word] {
+			_, ok := ParsePeriod(text, time.Now())
+			return ok
+		}
+	}
+
+	return false
+}
+
+// IsLinksRequest проверяет, просят ли подборку ссылок ("ссылки за неделю")
+func IsLinksRequest(text string) bool {
+	cleanText := strings.ToLower(text)
+
+	linksTriggers := []string{"ссылки за", "ссылок за", "какие ссылки", "подборка ссылок"}
+
+	for _, trigger := range linksTriggers {
+		if strings.Contains(cleanText, trigger) {
+			return true
+		}
+	}
+
+	// Английские фразы целыми словами, чтобы не ловить "symlinks" и "hyperlinks"
+	return linksPhraseRe.MatchString(cleanText)
+}
+
+// linksPhraseRe "links for the week", "links from the last 3 days", "shared links"
+var linksPhraseRe = regexp.MustCompile(`(?:^|[^\p{L}])(?:links\s+(?:for|from|over|since|this)|shared\s+links)(?:[^\p{L}]|$)`)
+
+// IsHistoryQuestion проверяет, спрашивают ли про что-то из прошлого переписки
+// ("кто говорил про отпуск и когда?"), а не просто болтают с ботом
+func IsHistoryQuestion(text string) bool {
+	cleanText := strings.ToLower(text)
+
+	questionTriggers := []string{
+		"кто говорил", "кто писал", "кто предлагал", "кто упоминал", "кто скидывал", "кто кидал", "кто сказал",
+		"говорил ли кто", "писал ли кто", "кто-нибудь писал", "кто-нибудь говорил",
+		"когда говорили", "когда обсуждали", "когда писали", "когда договорились",
+		"что говорили про", "что говорили о", "что писали про", "что писали о", "что решили",
+		"напомни, что", "напомни что",
+		"who said", "who mentioned", "who talked about", "when did we", "did anyone", "what did we decide",
+	}
+
+	for _, trigger := range questionTriggers {
+		if strings.Contains(cleanText, trigger) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequestedLanguage возвращает код языка, на котором просят ответить ("summary in english"),
+// или пустую строку, если язык не указан
+func RequestedLanguage(text string) string {
+	cleanText := strings.ToLower(text)
+
+	languageTriggers := map[string][]string{
+		"en": {"in english", "на английском", "по-английски", "по английски", "на англ"},
+		"ru": {"in russian", "на русском", "по-русски", "по русски"},
+	}
+
+	for code, triggers := range languageTriggers {
+		for _, trigger := range triggers {
+			if strings.Contains(cleanText, trigger) {
+				return code
+			}
+		}
+	}
+
+	return ""
+}
+
+// IsParticipantSummaryRequest проверяет, просят ли резюме по одному участнику
+func IsParticipantSummaryRequest(text string) bool {
+	cleanText := strings.ToLower(text)
+
+	participantTriggers := []string{
+		"что писал", "что писала", "о чем писал", "о чём писал",
+		"что говорил", "что говорила", "о чем говорил", "о чём говорил",
+	}
+
+	for _, trigger := range participantTriggers {
+		if strings.Contains(cleanText, trigger) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsThreadSummaryRequest проверяет, просят ли резюме ветки ответов ("саммари треда")
+func IsThreadSummaryRequest(text string) bool {
+	cleanText := strings.ToLower(text)
+
+	threadTriggers := []string{
+		"саммари треда", "резюме треда", "саммари ветки", "резюме ветки",
+		"о чем тред", "о чём тред", "о чем ветка", "о чём ветка", "перескажи тред", "перескажи ветку",
+		"thread summary", "summarize thread", "summarize the thread", "summary of the thread",
+	}
+
+	for _, trigger := range threadTriggers {
+		if strings.Contains(cleanText, trigger) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IsSummaryRequest проверяет, является ли сообщение запросом резюме
 func IsSummaryRequest(text string) bool {
 	cleanText := strings.ToLower(text)
 
 	summaryTriggers := []string{
-		"что было", "что происходило", "резюме", "саммари", "summary",
-		"сегодня", "вчера", "позавчера",
-		"дн", "день", "дня", "дней",
+		"что было", "что происходило", "о чем говорили", "о чём говорили", "что обсуждали",
+		"резюме", "саммари", "summary", "what happened",
 	}
 
 	for _, trigger := range summaryTriggers {
@@ -130,5 +307,26 @@ func IsSummaryRequest(text string) bool {
 		}
 	}
 
-	return false
+	// Без слов-триггеров достаточно только голого периода ("за последние 3 часа", "с понедельника"):
+	// "за час успеешь?" или "работаю с 9 до 18" - это разговор, а не запрос резюме
+	return IsPeriodOnly(text)
+}
+
+// Pluralize выбирает форму слова для числа n: "1 день", "2 дня", "5 дней"
+func Pluralize(n int, one, few, many string) string {
+	n %= 100
+	if n < 0 {
+		n = -n
+	}
+	if n >= 11 && n <= 14 {
+		return many
+	}
+	switch n % 10 {
+	case 1:
+		return one
+	case 2, 3, 4:
+		return few
+	default:
+		return many
+	}
 }
@@ -0,0 +1,49 @@
+package utils
+
+import "testing"
+
+func TestIsExportRequest(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"@bot экспорт", true},
+		{"@bot экспорт за неделю", true},
+		{"экспорт за вчера в html", true},
+		{"@bot выгрузи переписку", true},
+		{"сделай выгрузку за 3 дня", true},
+		{"export html", true},
+		{"@bot как сделать экспорт в csv?", false},
+		{"exported function?", false},
+		{"выгрузи в csv", false},
+		{"привет", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsExportRequest(tt.text); got != tt.want {
+			t.Errorf("IsExportRequest(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestIsLinksRequest(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"@bot ссылки за неделю", true},
+		{"какие ссылки кидали вчера?", true},
+		{"links for the week", true},
+		{"@bot links from the last 3 days", true},
+		{"shared links", true},
+		{"как удалить symlinks за день?", false},
+		{"hyperlinks for the docs are broken", false},
+		{"links", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsLinksRequest(tt.text); got != tt.want {
+			t.Errorf("IsLinksRequest(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
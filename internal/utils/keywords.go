@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// questionStopWords слова вопроса, по которым искать в переписке бессмысленно
+var questionStopWords = map[string]bool{
+	// вопросительные и служебные
+	"кто": true, "что": true, "чем": true, "чём": true, "когда": true, "где": true, "куда": true, "как": true,
+	"какой": true, "какая": true, "какие": true, "какую": true, "каких": true, "зачем": true, "почему": true,
+	"ли": true, "про": true, "для": true, "это": true, "этом": true, "эту": true, "этот": true, "нибудь": true,
+	"там": true, "тут": true, "был": true, "была": true, "было": true,
+	"были": true, "нас": true, "нам": true, "вас": true, "вам": true, "мне": true, "меня": true, "его": true,
+	"она": true, "они": true, "мы": true, "вы": true, "уже": true, "еще": true, "ещё": true, "все": true, "всё": true,
+	"или": true, "так": true, "тоже": true, "чат": true, "чате": true, "бот": true, "братан": true,
+	// глаголы "о прошлом", которые есть в самом вопросе
+	"говорил": true, "говорила": true, "говорили": true, "писал": true, "писала": true, "писали": true,
+	"обсуждали": true, "предлагал": true, "предлагала": true, "предлагали": true, "упоминал": true,
+	"упоминала": true, "упоминали": true, "решили": true, "скидывал": true, "скидывали": true, "кидал": true,
+	"кидали": true, "вспомни": true, "напомни": true, "сказал": true, "сказала": true, "сказали": true,
+	// английские
+	"who": true, "what": true, "when": true, "where": true, "did": true, "does": true, "the": true,
+	"about": true, "said": true, "say": true, "mentioned": true, "talked": true, "was": true, "were": true,
+	"anyone": true, "someone": true, "and": true, "for": true, "our": true, "chat": true,
+}
+
+// QuestionKeywords выделяет из вопроса слова для поиска по переписке, без упоминаний и команд.
+// Длинные слова обрезаются до основы, чтобы "отпуск" находил и "отпуске", и "отпуском"
+func QuestionKeywords(text string) []string {
+	return stems(text, func(word string) bool {
+		return !questionStopWords[word] && len([]rune(word)) >= 3
+	})
+}
+
+// SearchTerms разбивает поисковый запрос на основы слов, как QuestionKeywords, но без стоп-слов:
+// в запросе поиска каждое слово написано специально
+func SearchTerms(text string) []string {
+	return stems(text, func(word string) bool {
+		return len([]rune(word)) >= 2
+	})
+}
+
+// stems слова текста (кроме упоминаний и команд), прошедшие фильтр keep, обрезанные до основы, без повторов
+func stems(text string, keep func(word string) bool) []string {
+	var words []string
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		if strings.HasPrefix(field, "@") || strings.HasPrefix(field, "/") {
+			continue
+		}
+		words = append(words, strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if !keep(word) {
+			continue
+		}
+
+		stem := wordStem(word)
+		if !seen[stem] {
+			seen[stem] = true
+			result = append(result, stem)
+		}
+	}
+	return result
+}
+
+// wordStem грубая основа слова: отрезает окончание у длинных слов
+func wordStem(word string) string {
+	runes := []rune(word)
+	switch {
+	case len(runes) >= 7:
+		return string(runes[:len(runes)-2])
+	case len(runes) >= 5:
+		return string(runes[:len(runes)-1])
+	default:
+		return word
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuestionKeywords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"@zagichak_bot кто говорил про отпуск и когда?", []string{"отпус"}},
+		{"Кто-нибудь писал про билеты в Казань?", []string{"билет", "казан"}},
+		{"who mentioned the deadline?", []string{"deadli"}},
+		{"кто что где", nil},
+	}
+
+	for _, tt := range tests {
+		if got := QuestionKeywords(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("QuestionKeywords(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	got := SearchTerms("/search Шашлыки на даче в субботу")
+	want := []string{"шашлы", "на", "даче", "суббо"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchTerms = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	urlRe = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'«»]+`)
+	// otherSchemeRe ссылки не в веб: mailto:, tel: и т.п. ("host:8080" сюда не попадает)
+	otherSchemeRe = regexp.MustCompile(`(?i)^[a-z][a-z0-9+.-]*:[^0-9]`)
+)
+
+// ExtractURLs находит в тексте все ссылки http(s), без повторов и без хвостовой пунктуации
+func ExtractURLs(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, match := range urlRe.FindAllString(text, -1) {
+		link := trimURL(match)
+		if link == "" || seen[link] {
+			continue
+		}
+		seen[link] = true
+		urls = append(urls, link)
+	}
+	return urls
+}
+
+// NormalizeURL приводит ссылку из сущности Telegram к виду с протоколом ("example.com" -> "http://example.com").
+// Возвращает пустую строку, если это не веб-ссылка
+func NormalizeURL(link string) string {
+	link = trimURL(strings.TrimSpace(link))
+	if link == "" {
+		return ""
+	}
+	if !strings.Contains(link, "://") {
+		if otherSchemeRe.MatchString(link) {
+			return ""
+		}
+		link = "http://" + link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return link
+}
+
+// LinkDomain возвращает домен ссылки без www, в нижнем регистре
+func LinkDomain(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// trimURL отрезает знаки препинания, прилипшие к концу ссылки в тексте.
+// Закрывающую скобку оставляет, если в ссылке есть открывающая (как в ссылках на Википедию)
+func trimURL(link string) string {
+	for link != "" {
+		last := link[len(link)-1]
+		switch {
+		case strings.IndexByte(".,;:!?", last) >= 0:
+			link = link[:len(link)-1]
+		case last == ')' && strings.Count(link, "(") < strings.Count(link, ")"):
+			link = link[:len(link)-1]
+		default:
+			return link
+		}
+	}
+	return link
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"нет ссылок", "просто текст", nil},
+		{"точка в конце", "глянь https://example.com/page.", []string{"https://example.com/page"}},
+		{"повтор", "http://a.ru и снова http://a.ru, вот", []string{"http://a.ru"}},
+		{"скобки вокруг", "(см. https://go.dev/doc)", []string{"https://go.dev/doc"}},
+		{"скобки в ссылке", "https://en.wikipedia.org/wiki/Go_(programming_language)", []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"}},
+		{"кавычки", "«https://habr.com/ru/articles/1/»", []string{"https://habr.com/ru/articles/1/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractURLs(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractURLs(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := map[string]string{
+		"example.com":             "http://example.com",
+		"https://example.com/a,":  "https://example.com/a",
+		"tg://user?id=1":          "",
+		"mailto:someone@mail.com": "",
+	}
+
+	for in, want := range tests {
+		if got := NormalizeURL(in); got != want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLinkDomain(t *testing.T) {
+	if got := LinkDomain("https://WWW.YouTube.com/watch?v=1"); got != "youtube.com" {
+		t.Errorf("LinkDomain = %q, want youtube.com", got)
+	}
+}
@@ -0,0 +1,343 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeRangeKind как был задан период в тексте
+type TimeRangeKind string
+
+const (
+	// RangeDay - один календарный день Days дней назад (сегодня/вчера/позавчера)
+	RangeDay TimeRangeKind = "day"
+	// RangeLastDays - последние Days дней, включая сегодня
+	RangeLastDays TimeRangeKind = "last_days"
+	// RangeDates - явный диапазон календарных дней (даты, недели, "с понедельника")
+	RangeDates TimeRangeKind = "dates"
+	// RangeHours - интервал с точностью до часов и минут
+	RangeHours TimeRangeKind = "hours"
+)
+
+// TimeRange период, разобранный из текста: всегда полуинтервал [Start, End)
+type TimeRange struct {
+	Kind  TimeRangeKind
+	Start time.Time
+	End   time.Time
+	// Days - для RangeDay сколько дней назад, для остальных дневных периодов - число дней
+	Days int
+	// Hours - для "последних N часов" число часов, для интервала "с 10 до 14" 0
+	Hours int
+}
+
+// periodAnchors предлоги, с которых начинается голый период в запросе ("за 3 часа", "с понедельника")
+var periodAnchors = map[string]bool{
+	"за": true, "с": true, "со": true, "since": true, "for": true, "over": true, "last": true, "past": true, "the": true,
+}
+
+// wordNumerals числительные словами
+var wordNumerals = map[string]int{
+	"один": 1, "одна": 1, "одну": 1, "одного": 1, "one": 1,
+	"два": 2, "две": 2, "двух": 2, "пару": 2, "пара": 2, "two": 2, "couple": 2,
+	"три": 3, "трех": 3, "three": 3,
+	"четыре": 4, "четырех": 4, "four": 4,
+	"пять": 5, "пяти": 5, "five": 5,
+	"шесть": 6, "шести": 6, "six": 6,
+	"семь": 7, "семи": 7, "seven": 7,
+	"восемь": 8, "восьми": 8, "eight": 8,
+	"девять": 9, "девяти": 9, "nine": 9,
+	"десять": 10, "десяти": 10, "ten": 10,
+	"одиннадцать": 11, "двенадцать": 12, "twelve": 12,
+	"тринадцать": 13, "четырнадцать": 14, "пятнадцать": 15,
+	"шестнадцать": 16, "семнадцать": 17, "восемнадцать": 18, "девятнадцать": 19,
+	"двадцать": 20, "twenty": 20, "тридцать": 30,
+}
+
+// weekdayStems основы названий дней недели
+var weekdayStems = []struct {
+	stem string
+	day  time.Weekday
+}{
+	{"понедельник", time.Monday}, {"monday", time.Monday},
+	{"вторник", time.Tuesday}, {"tuesday", time.Tuesday},
+	{"сред", time.Wednesday}, {"wednesday", time.Wednesday},
+	{"четверг", time.Thursday}, {"thursday", time.Thursday},
+	{"пятниц", time.Friday}, {"friday", time.Friday},
+	{"суббот", time.Saturday}, {"saturday", time.Saturday},
+	{"воскресень", time.Sunday}, {"sunday", time.Sunday},
+}
+
+const (
+	// wordEnd конец слова: Go-шный \b не работает с кириллицей
+	wordEnd = `(?:[^\p{L}]|$)`
+	// lastWord необязательное "последние"/"the last"
+	lastWord = `(?:последн\p{L}*\s+|the\s+(?:last|past)\s+)?`
+)
+
+var (
+	numWordRe       = regexp.MustCompile(`\p{L}+`)
+	mentionRe       = regexp.MustCompile(`@\w+`)
+	compoundNumRe   = regexp.MustCompile(`(20|30) ([1-9])(\D|$)`)
+	dateRangeRe     = regexp.MustCompile(`(\d{1,2})\.(\d{1,2})(?:\.(\d{2,4}))?\s*(?:-|—|–|по|до|to)\s*(\d{1,2})\.(\d{1,2})(?:\.(\d{2,4}))?`)
+	singleDateRe    = regexp.MustCompile(`(?:^|[^\d.])(\d{1,2})\.(?:(\d{2})|(\d{1,2})\.(\d{2,4}))(?:[^\d.]|$)`)
+	clockRangeRe    = regexp.MustCompile(`(?:^|\s)(?:с|from)\s+(\d{1,2})(?::(\d{2}))?\s*(?:ч\p{L}*\s+)?(?:до|по|to|-|—|–)\s*(\d{1,2})(?::(\d{2}))?`)
+	hoursRe         = regexp.MustCompile(`(?:за|last|past|for)\s+` + lastWord + `(\d+)?\s*(?:час(?:а|ов)?|hours?)` + wordEnd)
+	prevWeekRe      = regexp.MustCompile(`(?:прошл\p{L}*|предыдущ\p{L}*)\s+недел\p{L}*|last\s+week`)
+	thisWeekRe      = regexp.MustCompile(`(?:эт\p{L}*|текущ\p{L}*)\s+недел\p{L}*|this\s+week`)
+	sinceWeekdayRe  = regexp.MustCompile(`(?:^|\s)(?:с|since)\s+(\p{L}+)`)
+	weeksRe         = regexp.MustCompile(`(?:за|last|past|for)\s+` + lastWord + `(\d+)?\s*(?:недел\p{L}*|weeks?)` + wordEnd)
+	daysRe          = regexp.MustCompile(`(?:(?:за|last|past|for)\s+)?` + lastWord + `(\d+)\s*(?:дн(?:я|ей)|день|days?)` + wordEnd)
+	singleDayRe     = regexp.MustCompile(`(?:за|last|past|for)\s+` + lastWord + `(?:день|сутки|day)` + wordEnd)
+	beforeYesterday = regexp.MustCompile(`позавчера|day\s+before\s+yesterday`)
+	yesterdayRe     = regexp.MustCompile(`вчера|yesterday`)
+	todayRe         = regexp.MustCompile(`сегодня|today`)
+)
+
+// ParsePeriod разбирает период из текста запроса на русском или английском:
+// "сегодня", "вчера", "за последние 3 часа", "за час", "за 5 дней", "за неделю",
+// "за прошлую неделю", "с понедельника", "12.10", "10.10 - 12.10", "с 10 до 14",
+// числа можно писать словами. Дата без года пишется с месяцем из двух цифр ("12.10", "5.09"),
+// чтобы "версия 1.2" не стала датой. Время считается относительно now в его таймзоне
+func ParsePeriod(text string, now time.Time) (TimeRange, bool) {
+	r, _, ok := parsePeriod(text, now)
+	return r, ok
+}
+
+// parsePeriod как ParsePeriod, но еще возвращает нормализованный текст без фрагмента с периодом
+func parsePeriod(text string, now time.Time) (TimeRange, string, bool) {
+	text = normalizePeriodText(text)
+	today := dayStart(now)
+	rest := func(loc []int) string {
+		return text[:loc[0]] + " " + text[loc[1]:]
+	}
+
+	if m := dateRangeRe.FindStringSubmatchIndex(text); m != nil {
+		g := submatches(text, m)
+		from, okFrom := parseDate(g[1], g[2], g[3], now)
+		to, okTo := parseDate(g[4], g[5], g[6], now)
+		if !okFrom || !okTo {
+			return TimeRange{}, "", false
+		}
+		if to.Before(from) {
+			from, to = to, from
+		}
+		return datesRange(from, to.AddDate(0, 0, 1)), rest(m), true
+	}
+
+	if m := singleDateRe.FindStringSubmatchIndex(text); m != nil {
+		g := submatches(text, m)
+		if date, ok := parseDate(g[1], g[2]+g[3], g[4], now); ok {
+			return datesRange(date, date.AddDate(0, 0, 1)), rest(m), true
+		}
+	}
+
+	if m := clockRangeRe.FindStringSubmatchIndex(text); m != nil {
+		if r, ok := clockRange(submatches(text, m), text, now); ok {
+			return r, rest(m), true
+		}
+	}
+
+	if m := hoursRe.FindStringSubmatchIndex(text); m != nil {
+		hours := 1
+		if g := submatches(text, m); g[1] != "" {
+			hours, _ = strconv.Atoi(g[1])
+		}
+		if hours > 0 {
+			return TimeRange{Kind: RangeHours, Start: now.Add(-time.Duration(hours) * time.Hour), End: now, Hours: hours}, rest(m), true
+		}
+	}
+
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+
+	if m := prevWeekRe.FindStringIndex(text); m != nil {
+		return datesRange(monday.AddDate(0, 0, -7), monday), rest(m), true
+	}
+
+	if m := thisWeekRe.FindStringIndex(text); m != nil {
+		return datesRange(monday, today.AddDate(0, 0, 1)), rest(m), true
+	}
+
+	for _, m := range sinceWeekdayRe.FindAllStringSubmatchIndex(text, -1) {
+		word := text[m[2]:m[3]]
+		for _, wd := range weekdayStems {
+			if strings.HasPrefix(word, wd.stem) {
+				back := (int(today.Weekday()) - int(wd.day) + 7) % 7
+				return datesRange(today.AddDate(0, 0, -back), today.AddDate(0, 0, 1)), rest(m), true
+			}
+		}
+	}
+
+	if m := weeksRe.FindStringSubmatchIndex(text); m != nil {
+		weeks := 1
+		if g := submatches(text, m); g[1] != "" {
+			weeks, _ = strconv.Atoi(g[1])
+		}
+		if weeks > 0 {
+			return lastDaysRange(today, weeks*7), rest(m), true
+		}
+	}
+
+	if m := daysRe.FindStringSubmatchIndex(text); m != nil {
+		if days, _ := strconv.Atoi(submatches(text, m)[1]); days > 0 {
+			return lastDaysRange(today, days), rest(m), true
+		}
+	}
+
+	if m := singleDayRe.FindStringIndex(text); m != nil {
+		return lastDaysRange(today, 1), rest(m), true
+	}
+
+	if m := beforeYesterday.FindStringIndex(text); m != nil {
+		return singleDay(today, 2), rest(m), true
+	}
+	if m := yesterdayRe.FindStringIndex(text); m != nil {
+		return singleDay(today, 1), rest(m), true
+	}
+	if m := todayRe.FindStringIndex(text); m != nil {
+		return singleDay(today, 0), rest(m), true
+	}
+
+	return TimeRange{}, "", false
+}
+
+// IsPeriodOnly проверяет, что весь текст - это период, заданный через "за"/"с"
+// ("за последние 3 часа", "с понедельника"), а не фраза, в которой случайно есть число и "час"
+func IsPeriodOnly(text string) bool {
+	text = mentionRe.ReplaceAllString(text, " ")
+	_, rest, ok := parsePeriod(text, time.Now())
+	if !ok {
+		return false
+	}
+	// Период должен начинаться с "за"/"с", а кроме него в тексте ничего не должно остаться
+	if !periodAnchors[numWordRe.FindString(normalizePeriodText(text))] {
+		return false
+	}
+	for _, word := range numWordRe.FindAllString(rest, -1) {
+		if !periodAnchors[word] {
+			return false
+		}
+	}
+	return !strings.ContainsAny(rest, "0123456789")
+}
+
+// submatches превращает индексы из FindStringSubmatchIndex в строки групп
+func submatches(text string, loc []int) []string {
+	groups := make([]string, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] >= 0 {
+			groups[i] = text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return groups
+}
+
+// normalizePeriodText приводит текст к нижнему регистру и заменяет числительные цифрами
+func normalizePeriodText(text string) string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	text = numWordRe.ReplaceAllStringFunc(text, func(word string) string {
+		if n, ok := wordNumerals[word]; ok {
+			return strconv.Itoa(n)
+		}
+		return word
+	})
+	// "двадцать четыре" -> "20 4" -> "24"
+	return compoundNumRe.ReplaceAllStringFunc(text, func(s string) string {
+		m := compoundNumRe.FindStringSubmatch(s)
+		tens, _ := strconv.Atoi(m[1])
+		units, _ := strconv.Atoi(m[2])
+		return strconv.Itoa(tens+units) + m[3]
+	})
+}
+
+// clockRange разбирает "с 10 до 14" как интервал времени сегодня (или вчера/позавчера, если так сказано)
+func clockRange(m []string, text string, now time.Time) (TimeRange, bool) {
+	fromHour, _ := strconv.Atoi(m[1])
+	fromMin, _ := strconv.Atoi(m[2])
+	toHour, _ := strconv.Atoi(m[3])
+	toMin, _ := strconv.Atoi(m[4])
+	if fromHour > 23 || toHour > 24 || fromMin > 59 || toMin > 59 {
+		return TimeRange{}, false
+	}
+
+	base := dayStart(now)
+	switch {
+	case beforeYesterday.MatchString(text):
+		base = base.AddDate(0, 0, -2)
+	case yesterdayRe.MatchString(text):
+		base = base.AddDate(0, 0, -1)
+	}
+
+	start := base.Add(time.Duration(fromHour)*time.Hour + time.Duration(fromMin)*time.Minute)
+	end := base.Add(time.Duration(toHour)*time.Hour + time.Duration(toMin)*time.Minute)
+	if !end.After(start) {
+		// "с 22 до 2" - через полночь
+		end = end.AddDate(0, 0, 1)
+	}
+	if start.After(now) {
+		// Это время сегодня еще не наступило - значит вчера
+		start, end = start.AddDate(0, 0, -1), end.AddDate(0, 0, -1)
+	}
+
+	return TimeRange{Kind: RangeHours, Start: start, End: end}, true
+}
+
+// parseDate собирает дату из строк дня, месяца и (необязательно) года
+func parseDate(dayStr, monthStr, yearStr string, now time.Time) (time.Time, bool) {
+	day, err := strconv.Atoi(dayStr)
+	if err != nil {
+		return time.Time{}, false
+	}
+	month, err := strconv.Atoi(monthStr)
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+
+	year := now.Year()
+	if yearStr != "" {
+		if year, err = strconv.Atoi(yearStr); err != nil {
+			return time.Time{}, false
+		}
+		if year < 100 {
+			year += 2000
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+	if date.Day() != day {
+		return time.Time{}, false
+	}
+	// Без года и дата в будущем - значит имели в виду прошлый год
+	if yearStr == "" && date.After(now) {
+		date = date.AddDate(-1, 0, 0)
+	}
+	return date, true
+}
+
+func singleDay(today time.Time, daysAgo int) TimeRange {
+	start := today.AddDate(0, 0, -daysAgo)
+	return TimeRange{Kind: RangeDay, Start: start, End: start.AddDate(0, 0, 1), Days: daysAgo}
+}
+
+func lastDaysRange(today time.Time, days int) TimeRange {
+	return TimeRange{
+		Kind:  RangeLastDays,
+		Start: today.AddDate(0, 0, -(days - 1)),
+		End:   today.AddDate(0, 0, 1),
+		Days:  days,
+	}
+}
+
+func datesRange(start, end time.Time) TimeRange {
+	days := 0
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		days++
+	}
+	return TimeRange{Kind: RangeDates, Start: start, End: end, Days: days}
+}
+
+// dayStart полночь дня, в который попадает t, в таймзоне t
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	// Пятница, 16 октября 2026, 15:30
+	now := time.Date(2026, 10, 16, 15, 30, 0, 0, loc)
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name  string
+		text  string
+		ok    bool
+		kind  TimeRangeKind
+		start time.Time
+		end   time.Time
+		days  int
+	}{
+		{"сегодня", "@bot что было сегодня", true, RangeDay, at(10, 16, 0, 0), at(10, 17, 0, 0), 0},
+		{"вчера", "что было вчера?", true, RangeDay, at(10, 15, 0, 0), at(10, 16, 0, 0), 1},
+		{"позавчера", "Что было ПОЗАВЧЕРА", true, RangeDay, at(10, 14, 0, 0), at(10, 15, 0, 0), 2},
+		{"english yesterday", "what happened yesterday", true, RangeDay, at(10, 15, 0, 0), at(10, 16, 0, 0), 1},
+
+		{"последние 3 часа", "за последние 3 часа", true, RangeHours, at(10, 16, 12, 30), now, 0},
+		{"часа словами", "что было за последние три часа", true, RangeHours, at(10, 16, 12, 30), now, 0},
+		{"за час", "что было за час", true, RangeHours, at(10, 16, 14, 30), now, 0},
+		{"последний час", "за последний час", true, RangeHours, at(10, 16, 14, 30), now, 0},
+		{"пару часов", "за пару часов", true, RangeHours, at(10, 16, 13, 30), now, 0},
+		{"двадцать четыре часа", "за двадцать четыре часа", true, RangeHours, at(10, 15, 15, 30), now, 0},
+		{"english hours", "summary for the last 2 hours", true, RangeHours, at(10, 16, 13, 30), now, 0},
+
+		{"с 10 до 14", "что было с 10 до 14", true, RangeHours, at(10, 16, 10, 0), at(10, 16, 14, 0), 0},
+		{"с минутами", "с 9:30 до 11:15", true, RangeHours, at(10, 16, 9, 30), at(10, 16, 11, 15), 0},
+		{"вчера с 10 до 14", "что было вчера с 10 до 14", true, RangeHours, at(10, 15, 10, 0), at(10, 15, 14, 0), 0},
+		{"еще не наступило", "с 18 до 20", true, RangeHours, at(10, 15, 18, 0), at(10, 15, 20, 0), 0},
+		{"через полночь", "с 22 до 2", true, RangeHours, at(10, 15, 22, 0), at(10, 16, 2, 0), 0},
+
+		{"дни цифрой", "что было за 3 дня", true, RangeLastDays, at(10, 14, 0, 0), at(10, 17, 0, 0), 3},
+		{"дни словами", "за последние пять дней", true, RangeLastDays, at(10, 12, 0, 0), at(10, 17, 0, 0), 5},
+		{"дни без за", "резюме 2 дня", true, RangeLastDays, at(10, 15, 0, 0), at(10, 17, 0, 0), 2},
+		{"за день", "что было за день", true, RangeLastDays, at(10, 16, 0, 0), at(10, 17, 0, 0), 1},
+		{"за неделю", "что было за неделю", true, RangeLastDays, at(10, 10, 0, 0), at(10, 17, 0, 0), 7},
+		{"две недели", "за две недели", true, RangeLastDays, at(10, 3, 0, 0), at(10, 17, 0, 0), 14},
+		{"english days", "last 3 days", true, RangeLastDays, at(10, 14, 0, 0), at(10, 17, 0, 0), 3},
+
+		{"прошлая неделя", "что было за прошлую неделю", true, RangeDates, at(10, 5, 0, 0), at(10, 12, 0, 0), 7},
+		{"на прошлой неделе", "о чем говорили на прошлой неделе", true, RangeDates, at(10, 5, 0, 0), at(10, 12, 0, 0), 7},
+		{"english last week", "last week", true, RangeDates, at(10, 5, 0, 0), at(10, 12, 0, 0), 7},
+		{"эта неделя", "что было на этой неделе", true, RangeDates, at(10, 12, 0, 0), at(10, 17, 0, 0), 5},
+		{"с понедельника", "что было с понедельника", true, RangeDates, at(10, 12, 0, 0), at(10, 17, 0, 0), 5},
+		{"со среды", "с среды", true, RangeDates, at(10, 14, 0, 0), at(10, 17, 0, 0), 3},
+		{"с пятницы - сегодня", "с пятницы", true, RangeDates, at(10, 16, 0, 0), at(10, 17, 0, 0), 1},
+		{"с воскресенья", "с воскресенья", true, RangeDates, at(10, 11, 0, 0), at(10, 17, 0, 0), 6},
+		{"since monday", "since Monday", true, RangeDates, at(10, 12, 0, 0), at(10, 17, 0, 0), 5},
+
+		{"одна дата", "что было 12.10", true, RangeDates, at(10, 12, 0, 0), at(10, 13, 0, 0), 1},
+		{"дата с годом", "резюме за 01.10.2026", true, RangeDates, at(10, 1, 0, 0), at(10, 2, 0, 0), 1},
+		{"дата в будущем - прошлый год", "что было 20.12", true, RangeDates,
+			time.Date(2025, 12, 20, 0, 0, 0, 0, loc), time.Date(2025, 12, 21, 0, 0, 0, 0, loc), 1},
+		{"диапазон дат", "что было 10.10 - 12.10", true, RangeDates, at(10, 10, 0, 0), at(10, 13, 0, 0), 3},
+		{"диапазон с по", "с 10.10 по 12.10", true, RangeDates, at(10, 10, 0, 0), at(10, 13, 0, 0), 3},
+		{"диапазон наоборот", "12.10-10.10", true, RangeDates, at(10, 10, 0, 0), at(10, 13, 0, 0), 3},
+
+		{"нет периода", "привет, как дела?", false, "", time.Time{}, time.Time{}, 0},
+		{"день без числа", "хорошего дня", false, "", time.Time{}, time.Time{}, 0},
+		{"час внутри слова", "за частую бывает", false, "", time.Time{}, time.Time{}, 0},
+		{"кривая дата", "что было 31.02", false, "", time.Time{}, time.Time{}, 0},
+		{"кривой диапазон", "10.13 - 12.13", false, "", time.Time{}, time.Time{}, 0},
+		{"номер версии", "@bot версия 1.2 вышла", false, "", time.Time{}, time.Time{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParsePeriod(tt.text, now)
+			if ok != tt.ok {
+				t.Fatalf("ParsePeriod(%q) ok = %v, want %v (got %+v)", tt.text, ok, tt.ok, got)
+			}
+			if !ok {
+				return
+			}
+			if got.Kind != tt.kind {
+				t.Errorf("kind = %q, want %q", got.Kind, tt.kind)
+			}
+			if !got.Start.Equal(tt.start) {
+				t.Errorf("start = %v, want %v", got.Start, tt.start)
+			}
+			if !got.End.Equal(tt.end) {
+				t.Errorf("end = %v, want %v", got.End, tt.end)
+			}
+			if got.Days != tt.days {
+				t.Errorf("days = %d, want %d", got.Days, tt.days)
+			}
+		})
+	}
+}
+
+func TestParsePeriodHours(t *testing.T) {
+	now := time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		text  string
+		hours int
+	}{
+		{"за последние 2 часа", 2},
+		{"за час", 1},
+		{"за последние двенадцать часов", 12},
+		{"past hour", 1},
+		{"с 10 до 14", 0},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParsePeriod(tt.text, now)
+		if !ok || got.Kind != RangeHours {
+			t.Errorf("ParsePeriod(%q) = %+v, %v, want hours range", tt.text, got, ok)
+			continue
+		}
+		if got.Hours != tt.hours {
+			t.Errorf("ParsePeriod(%q).Hours = %d, want %d", tt.text, got.Hours, tt.hours)
+		}
+	}
+}
+
+func TestIsSummaryRequest(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"что было сегодня", true},
+		{"резюме", true},
+		{"за последние 3 часа", true},
+		{"с понедельника", true},
+		{"хорошего дня всем", false},
+		{"как прошел твой день?", false},
+		{"привет", false},
+		{"@bot за последние 3 часа", true},
+		{"за прошлую неделю", true},
+		{"с 10.10 по 12.10", true},
+		{"@bot версия 1.2 вышла", false},
+		{"@bot работаю с 9 до 18, что посоветуешь", false},
+		{"@bot за час успеешь?", false},
+		{"@bot у меня 3 дня отпуска", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSummaryRequest(tt.text); got != tt.want {
+			t.Errorf("IsSummaryRequest(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
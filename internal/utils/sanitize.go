@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// telegramTags теги, которые Telegram понимает в режиме HTML
+var telegramTags = map[string]bool{
+	"b": true, "strong": true,
+	"i": true, "em": true,
+	"u": true, "ins": true,
+	"s": true, "strike": true, "del": true,
+	"span": true, "tg-spoiler": true,
+	"a": true, "tg-emoji": true,
+	"code": true, "pre": true,
+	"blockquote": true,
+}
+
+var (
+	htmlTagRe     = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9-]*)([^<>]*)>`)
+	htmlAttrRe    = regexp.MustCompile(`([a-zA-Z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	htmlEntityRe  = regexp.MustCompile(`^&(?:lt|gt|amp|quot|#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6});`)
+	codeLangRe    = regexp.MustCompile(`^language-[a-zA-Z0-9_+-]+$`)
+	emojiIDRe     = regexp.MustCompile(`^[0-9]+$`)
+	htmlLinkRe    = regexp.MustCompile(`(?s)<a href="([^"]*)">(.*?)</a>`)
+	htmlAnyTagRe  = regexp.MustCompile(`<[^<>]*>`)
+	allowedSchema = []string{"http://", "https://", "tg://", "mailto:"}
+)
+
+// SanitizeTelegramHTML приводит HTML (обычно от модели) к виду, который примет Telegram:
+// оставляет только поддерживаемые теги и атрибуты, экранирует все остальное
+// и закрывает незакрытые теги
+func SanitizeTelegramHTML(text string) string {
+	var out strings.Builder
+	var stack []string
+
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			if m := htmlTagRe.FindStringSubmatch(text[i:]); m != nil {
+				closing, name, attrs := m[1] == "/", strings.ToLower(m[2]), m[3]
+
+				if name == "br" {
+					out.WriteString("\n")
+					i += len(m[0])
+					continue
+				}
+
+				if looksLikeTag(name, attrs, closing) {
+					if closing {
+						stack = closeTag(&out, stack, name)
+					} else if tag, ok := openTag(name, attrs); ok {
+						out.WriteString(tag)
+						stack = append(stack, name)
+					}
+					// Тег с негодными атрибутами выкидываем, текст внутри остается
+					i += len(m[0])
+					continue
+				}
+			}
+			out.WriteString("&lt;")
+			i++
+		case '>':
+			out.WriteString("&gt;")
+			i++
+		case '&':
+			if entity := htmlEntityRe.FindString(text[i:]); entity != "" {
+				out.WriteString(entity)
+				i += len(entity)
+			} else {
+				out.WriteString("&amp;")
+				i++
+			}
+		default:
+			out.WriteByte(text[i])
+			i++
+		}
+	}
+
+	for j := len(stack) - 1; j >= 0; j-- {
+		out.WriteString("</" + stack[j] + ">")
+	}
+
+	return out.String()
+}
+
+// looksLikeTag отличает настоящий поддерживаемый тег от текста вроде "a<b & c>d"
+func looksLikeTag(name, attrs string, closing bool) bool {
+	if !telegramTags[name] {
+		return false
+	}
+
+	attrs = strings.TrimSpace(attrs)
+	if closing {
+		return attrs == ""
+	}
+
+	switch name {
+	case "a", "span", "tg-emoji", "code", "blockquote":
+		return attrs == "" || htmlAttrRe.MatchString(attrs) || attrs == "expandable"
+	default:
+		return attrs == ""
+	}
+}
+
+// openTag собирает открывающий тег только с разрешенными атрибутами
+func openTag(name, attrs string) (string, bool) {
+	values := make(map[string]string)
+	for _, m := range htmlAttrRe.FindAllStringSubmatch(attrs, -1) {
+		values[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+
+	switch name {
+	case "a":
+		href := strings.TrimSpace(values["href"])
+		for _, schema := range allowedSchema {
+			if strings.HasPrefix(strings.ToLower(href), schema) {
+				return `<a href="` + EscapeHTML(href) + `">`, true
+			}
+		}
+		return "", false
+	case "span":
+		if values["class"] != "tg-spoiler" {
+			return "", false
+		}
+		return `<span class="tg-spoiler">`, true
+	case "tg-emoji":
+		if !emojiIDRe.MatchString(values["emoji-id"]) {
+			return "", false
+		}
+		return `<tg-emoji emoji-id="` + values["emoji-id"] + `">`, true
+	case "code":
+		if codeLangRe.MatchString(values["class"]) {
+			return `<code class="` + values["class"] + `">`, true
+		}
+		return "<code>", true
+	case "blockquote":
+		if strings.Contains(strings.ToLower(attrs), "expandable") {
+			return "<blockquote expandable>", true
+		}
+		return "<blockquote>", true
+	default:
+		return "<" + name + ">", true
+	}
+}
+
+// closeTag закрывает тег name и все теги, открытые внутри него; лишние закрывающие теги выкидывает
+func closeTag(out *strings.Builder, stack []string, name string) []string {
+	idx := -1
+	for j := len(stack) - 1; j >= 0; j-- {
+		if stack[j] == name {
+			idx = j
+			break
+		}
+	}
+	if idx < 0 {
+		return stack
+	}
+
+	for j := len(stack) - 1; j >= idx; j-- {
+		out.WriteString("</" + stack[j] + ">")
+	}
+	return stack[:idx]
+}
+
+// StripHTML превращает HTML-сообщение в простой текст: ссылки раскрываются
+// в "текст (адрес)", теги убираются, сущности раскодируются
+func StripHTML(text string) string {
+	text = htmlLinkRe.ReplaceAllStringFunc(text, func(link string) string {
+		m := htmlLinkRe.FindStringSubmatch(link)
+		href, label := html.UnescapeString(m[1]), m[2]
+		if strings.HasPrefix(href, "tg://") {
+			return label
+		}
+		return label + " (" + href + ")"
+	})
+	text = htmlAnyTagRe.ReplaceAllString(text, "")
+	return html.UnescapeString(text)
+}
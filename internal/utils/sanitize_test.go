@@ -0,0 +1,69 @@
+package utils
+
+import "testing"
+
+func TestSanitizeTelegramHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"простой текст", "просто текст", "просто текст"},
+		{"поддерживаемые теги", "<b>жирный</b> и <i>курсив</i>", "<b>жирный</b> и <i>курсив</i>"},
+		{"заглавные теги", "<B>жирный</B>", "<b>жирный</b>"},
+		{"одинокий <", "a < b", "a &lt; b"},
+		{"сравнение без пробелов", "a<b && c>d", "a&lt;b &amp;&amp; c&gt;d"},
+		{"одинокий >", "x > 5", "x &gt; 5"},
+		{"script", "<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"div", "<div>текст</div>", "&lt;div&gt;текст&lt;/div&gt;"},
+		{"br", "строка<br>еще<br/>", "строка\nеще\n"},
+		{"ссылка", `<a href="https://example.com/?a=1&b=2">тык</a>`, `<a href="https://example.com/?a=1&amp;b=2">тык</a>`},
+		{"javascript в ссылке", `<a href="javascript:alert(1)">тык</a>`, "тык"},
+		{"javascript с пробелами", `<a href=" JavaScript:alert(1)">тык</a>`, "тык"},
+		{"ссылка без схемы", `<a href="example.com">тык</a>`, "тык"},
+		{"tg ссылка", `<a href="tg://user?id=1">Вася</a>`, `<a href="tg://user?id=1">Вася</a>`},
+		{"лишние атрибуты", `<b style="color:red">x</b>`, `&lt;b style="color:red"&gt;x`},
+		{"спойлер", `<span class="tg-spoiler">тайна</span>`, `<span class="tg-spoiler">тайна</span>`},
+		{"чужой span", `<span class="red">текст</span>`, "текст"},
+		{"язык кода", `<pre><code class="language-go">x := 1</code></pre>`, `<pre><code class="language-go">x := 1</code></pre>`},
+		{"незакрытый тег", "<b>жирный", "<b>жирный</b>"},
+		{"незакрытые вложенные", "<b><i>текст", "<b><i>текст</i></b>"},
+		{"перепутанная вложенность", "<b><i>текст</b></i>", "<b><i>текст</i></b>"},
+		{"лишний закрывающий", "текст</b>", "текст"},
+		{"закрывающий не того", "<i>текст</b></i>", "<i>текст</i>"},
+		{"готовые сущности", "a &amp; b &lt;c&gt; &quot;d&quot; &#39;e&#39; &#x1F600;", "a &amp; b &lt;c&gt; &quot;d&quot; &#39;e&#39; &#x1F600;"},
+		{"голый амперсанд", "Tom & Jerry", "Tom &amp; Jerry"},
+		{"неизвестная сущность", "&nbsp;", "&amp;nbsp;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeTelegramHTML(tt.text); got != tt.want {
+				t.Errorf("SanitizeTelegramHTML(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"простой текст", "просто текст", "просто текст"},
+		{"теги", "<b>жирный</b> и <i>курсив</i>", "жирный и курсив"},
+		{"ссылка", `<a href="https://example.com/?a=1&amp;b=2">тык</a>`, "тык (https://example.com/?a=1&b=2)"},
+		{"упоминание", `<a href="tg://user?id=1">Вася</a>`, "Вася"},
+		{"сущности", "a &lt; b &amp;&amp; c &gt; d", "a < b && c > d"},
+		{"вложенные", "<blockquote><b>цитата</b></blockquote>", "цитата"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripHTML(tt.text); got != tt.want {
+				t.Errorf("StripHTML(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
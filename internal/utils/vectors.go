@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// NormalizeVector приводит вектор к единичной длине, чтобы косинусная близость считалась простым скалярным произведением
+func NormalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+
+	norm := float32(math.Sqrt(sum))
+	result := make([]float32, len(v))
+	for i, x := range v {
+		result[i] = x / norm
+	}
+	return result
+}
+
+// EncodeVector упаковывает вектор в байты для хранения в БД (float32, little-endian)
+func EncodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+// DecodeVector обратное к EncodeVector
+func DecodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
+
+// DotProduct скалярное произведение; для нормированных векторов это косинусная близость.
+// Векторы разной длины (другая модель) считаются непохожими
+func DotProduct(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestVectorRoundTrip(t *testing.T) {
+	v := []float32{0.5, -1.25, 3, 0}
+	if got := DecodeVector(EncodeVector(v)); !reflect.DeepEqual(got, v) {
+		t.Errorf("DecodeVector(EncodeVector(%v)) = %v", v, got)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	a := NormalizeVector([]float32{3, 4})
+	b := NormalizeVector([]float32{6, 8})
+	c := NormalizeVector([]float32{-4, 3})
+
+	if got := DotProduct(a, b); math.Abs(float64(got)-1) > 1e-6 {
+		t.Errorf("DotProduct(a, b) = %v, want 1", got)
+	}
+	if got := DotProduct(a, c); math.Abs(float64(got)) > 1e-6 {
+		t.Errorf("DotProduct(a, c) = %v, want 0", got)
+	}
+	if got := DotProduct(a, []float32{1}); got != 0 {
+		t.Errorf("DotProduct with different lengths = %v, want 0", got)
+	}
+}